@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// Condition types specific to Terraform resources.
+const (
+	// TypeCancelled indicates whether the last Terraform operation was
+	// cancelled on request.
+	TypeCancelled xpv1.ConditionType = "Cancelled"
+
+	// TypeTerraformError indicates whether the last Terraform operation
+	// failed, and why.
+	TypeTerraformError xpv1.ConditionType = "TerraformError"
+)
+
+// Condition reasons specific to Terraform resources.
+const (
+	ReasonCancelRequested xpv1.ConditionReason = "CancelRequested"
+	ReasonNotCancelled    xpv1.ConditionReason = "NotCancelled"
+
+	ReasonStateLocked        xpv1.ConditionReason = "StateLocked"
+	ReasonThrottled          xpv1.ConditionReason = "Throttled"
+	ReasonAuthFailure        xpv1.ConditionReason = "AuthFailure"
+	ReasonConfigError        xpv1.ConditionReason = "ConfigError"
+	ReasonNetworkError       xpv1.ConditionReason = "NetworkError"
+	ReasonImportBlocked      xpv1.ConditionReason = "ImportBlocked"
+	ReasonDeletionProtected  xpv1.ConditionReason = "DeletionProtected"
+	ReasonDestructiveChanges xpv1.ConditionReason = "DestructiveChanges"
+	ReasonPolicyViolation    xpv1.ConditionReason = "PolicyViolation"
+	ReasonMigrationRequired  xpv1.ConditionReason = "MigrationRequired"
+	ReasonInvalidVariables   xpv1.ConditionReason = "InvalidVariables"
+	ReasonUnknownError       xpv1.ConditionReason = "UnknownError"
+	ReasonNoError            xpv1.ConditionReason = "NoError"
+)
+
+// Cancelled returns a condition that indicates a Terraform operation was
+// interrupted because cancellation was requested.
+func Cancelled(msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeCancelled,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCancelRequested,
+		Message:            msg,
+	}
+}
+
+// NotCancelled returns a condition that indicates Terraform operations are
+// running normally.
+func NotCancelled() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeCancelled,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNotCancelled,
+	}
+}
+
+// TerraformFailed returns a condition that indicates the last Terraform
+// operation failed with an error of the supplied class.
+func TerraformFailed(class xpv1.ConditionReason, err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeTerraformError,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             class,
+		Message:            err.Error(),
+	}
+}
+
+// TerraformSucceeded returns a condition that indicates the last Terraform
+// operation succeeded.
+func TerraformSucceeded() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeTerraformError,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoError,
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Enforcement actions of a PlanPolicy.
+const (
+	// PolicyEnforce blocks plans that violate the policy.
+	PolicyEnforce = "Enforce"
+
+	// PolicyWarn reports violations of the policy without blocking plans.
+	PolicyWarn = "Warn"
+)
+
+// PlanPolicySpec defines the desired state of a PlanPolicy.
+type PlanPolicySpec struct {
+	// Selector selects the Terraform resources the policy applies to by
+	// label. The policy applies to all Terraform resources if it is unset.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Namespaces restricts the policy to Terraform resources composed for
+	// claims in these namespaces, as recorded by the
+	// crossplane.io/claim-namespace label.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// EnforcementAction determines whether violations block plans from
+	// being applied, or are only reported.
+	// +kubebuilder:validation:Enum=Enforce;Warn
+	// +kubebuilder:default=Enforce
+	// +optional
+	EnforcementAction string `json:"enforcementAction,omitempty"`
+
+	// Rules that every plan must satisfy.
+	// +kubebuilder:validation:MinItems=1
+	Rules []PlanPolicyRule `json:"rules"`
+}
+
+// A PlanPolicyRule is a CEL expression a plan must satisfy.
+type PlanPolicyRule struct {
+	// Name of the rule.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Expression is a CEL expression that must evaluate to true for a plan
+	// to satisfy the rule. The variable plan holds the plan in Terraform's
+	// JSON plan format, and object holds the name, labels and annotations of
+	// the Terraform resource being planned.
+	// +kubebuilder:validation:Required
+	Expression string `json:"expression"`
+
+	// Message reported when a plan violates the rule.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="ENFORCEMENT",type="string",JSONPath=".spec.enforcementAction"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,terraform}
+
+// A PlanPolicy constrains the Terraform plans that may be applied.
+type PlanPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PlanPolicySpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// PlanPolicyList contains a list of PlanPolicy
+type PlanPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PlanPolicy `json:"items"`
+}
+
+// PlanPolicyGroupVersionKind is the GroupVersionKind for the PlanPolicy
+// resource.
+var PlanPolicyGroupVersionKind = schema.GroupVersionKind{
+	Group:   Group,
+	Version: Version,
+	Kind:    "PlanPolicy",
+}
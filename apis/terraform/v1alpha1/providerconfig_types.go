@@ -18,8 +18,10 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
 )
 
 // ProviderConfigSpec defines the desired state of ProviderConfig
@@ -35,7 +37,8 @@ type ProviderConfigSpec struct {
 	// +optional
 	WorkingDirectory string `json:"workingDirectory,omitempty"`
 
-	// Environment variables to set for all Terraform executions.
+	// Environment variables to set for all Terraform executions. Variables
+	// set from the credentials override them.
 	// +optional
 	Environment map[string]string `json:"environment,omitempty"`
 
@@ -56,26 +59,313 @@ type ProviderConfigSpec struct {
 	// Endpoints defines custom endpoints for Terraform providers.
 	// +optional
 	Endpoints map[string]string `json:"endpoints,omitempty"`
+
+	// Probe the ProviderConfig when it is reconciled, rather than only
+	// checking that its credentials exist.
+	// +optional
+	Probe *ProviderConfigProbe `json:"probe,omitempty"`
+}
+
+// A ProviderConfigProbe configures how a ProviderConfig is probed. The
+// Terraform binary is always checked for the configured TerraformVersion.
+type ProviderConfigProbe struct {
+	// Backend initialises the configured backend to check that it is
+	// reachable.
+	// +optional
+	Backend bool `json:"backend,omitempty"`
 }
 
-// ProviderCredentials defines the credentials for the Terraform provider
+// ProviderCredentials defines the credentials for the Terraform provider.
+// Credentials are read from their source whenever a resource using them is
+// reconciled, so rotated credentials are picked up without restarting the
+// provider. With an InjectedIdentity, Terraform uses the identity of the
+// provider's pod, e.g. through the environment variables and token files
+// injected into it.
 type ProviderCredentials struct {
 	// Source of the provider credentials.
-	// +kubebuilder:validation:Enum=None;Secret;InjectedIdentity;Environment;Filesystem
+	// +kubebuilder:validation:Enum=None;Secret;InjectedIdentity;Environment;Filesystem;Vault
 	Source xpv1.CredentialsSource `json:"source"`
 
 	// CommonCredentialSelectors provides common selectors for extracting
-	// credentials.
+	// credentials. A Filesystem source may be a projected or CSI mounted
+	// volume.
 	xpv1.CommonCredentialSelectors `json:",inline"`
+
+	// EnvironmentVariable is set to the credentials when Terraform is run,
+	// e.g. GOOGLE_CREDENTIALS.
+	// +optional
+	EnvironmentVariable string `json:"environmentVariable,omitempty"`
+
+	// FileEnvironmentVariable is set to the path of a file holding the
+	// credentials when Terraform is run, e.g. GOOGLE_APPLICATION_CREDENTIALS
+	// or AWS_SHARED_CREDENTIALS_FILE. The file is written to a memory backed
+	// filesystem for each reconcile, and removed afterwards.
+	// +optional
+	FileEnvironmentVariable string `json:"fileEnvironmentVariable,omitempty"`
+
+	// Adapter translates the credentials into the environment variables and
+	// files a cloud's Terraform provider reads.
+	// +optional
+	Adapter *CredentialsAdapter `json:"adapter,omitempty"`
+
+	// WorkloadIdentity exchanges a Kubernetes service account token for
+	// short-lived cloud credentials, so that no long-lived keys are stored.
+	// Requires the InjectedIdentity source.
+	// +optional
+	WorkloadIdentity *WorkloadIdentity `json:"workloadIdentity,omitempty"`
+
+	// Vault reads the credentials from HashiCorp Vault. Requires the Vault
+	// source.
+	// +optional
+	Vault *VaultCredentials `json:"vault,omitempty"`
+}
+
+// CredentialsSourceVault reads credentials from HashiCorp Vault.
+const CredentialsSourceVault xpv1.CredentialsSource = "Vault"
+
+// A CredentialsAdapterType is a kind of credentials an adapter translates.
+type CredentialsAdapterType string
+
+// Credentials adapter types.
+const (
+	// CredentialsAdapterAWS translates a JSON object of aws_access_key_id,
+	// aws_secret_access_key and optionally aws_session_token and region, or
+	// an AWS shared credentials file.
+	CredentialsAdapterAWS CredentialsAdapterType = "AWS"
+
+	// CredentialsAdapterGCP translates a Google Cloud service account key.
+	CredentialsAdapterGCP CredentialsAdapterType = "GCP"
+
+	// CredentialsAdapterAzure translates the JSON object of an Azure service
+	// principal's client ID, client secret, tenant ID and optionally
+	// subscription ID, as output by az ad sp create-for-rbac --sdk-auth.
+	CredentialsAdapterAzure CredentialsAdapterType = "Azure"
+
+	// CredentialsAdapterEnvMap translates a JSON object of environment
+	// variable names to values.
+	CredentialsAdapterEnvMap CredentialsAdapterType = "EnvMap"
+)
+
+// A CredentialsAdapter translates credentials into the environment variables
+// and files a cloud's Terraform provider reads.
+type CredentialsAdapter struct {
+	// Type of the credentials.
+	// +kubebuilder:validation:Enum=AWS;GCP;Azure;EnvMap
+	Type CredentialsAdapterType `json:"type"`
+
+	// Profile of an AWS shared credentials file to use. Defaults to the
+	// default profile.
+	// +optional
+	Profile string `json:"profile,omitempty"`
+}
+
+// A WorkloadIdentityType is a cloud a service account token is exchanged
+// with.
+type WorkloadIdentityType string
+
+// Workload identity types.
+const (
+	// WorkloadIdentityAWS assumes an AWS IAM role with web identity, as IRSA
+	// does.
+	WorkloadIdentityAWS WorkloadIdentityType = "AWS"
+
+	// WorkloadIdentityGCP exchanges the token through a Google Cloud
+	// workload identity pool provider, optionally impersonating a service
+	// account.
+	WorkloadIdentityGCP WorkloadIdentityType = "GCP"
+
+	// WorkloadIdentityAzure exchanges the token through a federated
+	// credential of an Azure application or managed identity.
+	WorkloadIdentityAzure WorkloadIdentityType = "Azure"
+)
+
+// A WorkloadIdentity exchanges a Kubernetes service account token for
+// short-lived cloud credentials. The token is requested for each reconcile,
+// and requested again while Terraform runs, once 80% of its lifetime has
+// passed.
+type WorkloadIdentity struct {
+	// Type of the cloud the token is exchanged with.
+	// +kubebuilder:validation:Enum=AWS;GCP;Azure
+	Type WorkloadIdentityType `json:"type"`
+
+	// Role the token is exchanged for: the ARN of an AWS IAM role, the email
+	// of a Google Cloud service account to impersonate, or the client ID of
+	// an Azure application or managed identity. Required for AWS and Azure.
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// Audience of the token. Defaults to sts.amazonaws.com for AWS, the
+	// default audience of the workload identity pool provider for GCP, and
+	// api://AzureADTokenExchange for Azure.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+
+	// WorkloadIdentityProvider is the resource name of a Google Cloud
+	// workload identity pool provider, i.e.
+	// projects/<number>/locations/global/workloadIdentityPools/<pool>/providers/<provider>.
+	// Required for GCP.
+	// +optional
+	WorkloadIdentityProvider string `json:"workloadIdentityProvider,omitempty"`
+
+	// TenantID of the Azure application or managed identity. Required for
+	// Azure.
+	// +optional
+	TenantID string `json:"tenantID,omitempty"`
+
+	// ServiceAccountRef is the service account the token is requested for.
+	// Defaults to the provider's own. The provider must be allowed to create
+	// tokens for it.
+	// +optional
+	ServiceAccountRef *ServiceAccountReference `json:"serviceAccountRef,omitempty"`
+
+	// ExpirationSeconds is the requested lifetime of the token. Defaults to
+	// 3600.
+	// +kubebuilder:validation:Minimum=600
+	// +optional
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+}
+
+// VaultCredentials are read from HashiCorp Vault, or a Vault compatible
+// server, each time a resource using them is reconciled. Leases on them are
+// revoked once Terraform has run.
+type VaultCredentials struct {
+	// Address of the Vault server, e.g. https://vault.example.com:8200.
+	Address string `json:"address"`
+
+	// Namespace of Vault Enterprise to use.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// CABundleSecretRef selects a PEM encoded CA bundle to verify the
+	// server's certificate with. Defaults to the system's.
+	// +optional
+	CABundleSecretRef *xpv1.SecretKeySelector `json:"caBundleSecretRef,omitempty"`
+
+	// Auth configures how the provider logs in to Vault.
+	Auth VaultKubernetesAuth `json:"auth"`
+
+	// Secrets to read, whose values Terraform is run with.
+	// +kubebuilder:validation:MinItems=1
+	Secrets []VaultSecret `json:"secrets"`
+}
+
+// VaultKubernetesAuth logs in to Vault with the Kubernetes auth method,
+// using a token of a Kubernetes service account.
+type VaultKubernetesAuth struct {
+	// Role to log in as.
+	Role string `json:"role"`
+
+	// MountPath of the Kubernetes auth method. Defaults to kubernetes.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// ServiceAccountRef is the service account the token is requested for.
+	// Defaults to the provider's own. The provider must be allowed to create
+	// tokens for it.
+	// +optional
+	ServiceAccountRef *ServiceAccountReference `json:"serviceAccountRef,omitempty"`
+
+	// Audience of the token, which the role must accept. Defaults to the
+	// audience of the Kubernetes API server.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+}
+
+// A VaultSecretEngine is a kind of secrets engine credentials are read from.
+type VaultSecretEngine string
+
+// Vault secrets engines.
+const (
+	// VaultSecretAWS reads credentials from the AWS secrets engine, e.g.
+	// aws/creds/<role> or aws/sts/<role>.
+	VaultSecretAWS VaultSecretEngine = "AWS"
+
+	// VaultSecretGCP reads a service account key from the Google Cloud
+	// secrets engine, e.g. gcp/roleset/<roleset>/key, or an OAuth access
+	// token, e.g. gcp/roleset/<roleset>/token.
+	VaultSecretGCP VaultSecretEngine = "GCP"
+
+	// VaultSecretAzure reads service principal credentials from the Azure
+	// secrets engine, e.g. azure/creds/<role>.
+	VaultSecretAzure VaultSecretEngine = "Azure"
+
+	// VaultSecretKV reads the values of a secret of a KV secrets engine of
+	// either version, e.g. secret/data/<name>.
+	VaultSecretKV VaultSecretEngine = "KV"
+)
+
+// A VaultSecret is a secret read from Vault.
+type VaultSecret struct {
+	// Engine of the secret, which determines the environment variables and
+	// files Terraform is run with.
+	// +kubebuilder:validation:Enum=AWS;GCP;Azure;KV
+	Engine VaultSecretEngine `json:"engine"`
+
+	// Path of the secret.
+	Path string `json:"path"`
+
+	// TTL requested for dynamic credentials, for secrets engines that
+	// support it.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Env maps the keys of a KV secret to the environment variables set to
+	// their values. Defaults to a variable named after each key.
+	// +optional
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// A CredentialLease is a lease on credentials read from Vault for the last
+// run. Their values are never recorded.
+type CredentialLease struct {
+	// Path the credentials were read from.
+	Path string `json:"path"`
+
+	// LeaseID of the credentials. Empty if they aren't leased, e.g. the
+	// values of a KV secret.
+	// +optional
+	LeaseID string `json:"leaseID,omitempty"`
+
+	// TTL of the lease.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Renewable is true if the lease can be renewed.
+	// +optional
+	Renewable bool `json:"renewable,omitempty"`
+
+	// Variables set from the credentials.
+	// +optional
+	Variables []string `json:"variables,omitempty"`
+}
+
+// A ServiceAccountReference is a reference to a Kubernetes service account.
+type ServiceAccountReference struct {
+	// Name of the service account.
+	Name string `json:"name"`
+
+	// Namespace of the service account.
+	Namespace string `json:"namespace"`
 }
 
 // ProviderConfigStatus defines the observed state of ProviderConfig
 type ProviderConfigStatus struct {
 	xpv1.ProviderConfigStatus `json:",inline"`
+
+	// TerraformVersion is the version of the Terraform binary found by the
+	// last probe.
+	// +optional
+	TerraformVersion string `json:"terraformVersion,omitempty"`
+
+	// LastProbeTime is when the ProviderConfig was last probed.
+	// +optional
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="USERS",type="integer",JSONPath=".status.users"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="SECRET-NAME",type="string",JSONPath=".spec.credentials.secretRef.name",priority=1
 // +kubebuilder:resource:scope=Cluster,categories={crossplane,providerconfig,terraform}
@@ -101,7 +391,9 @@ type ProviderConfigList struct {
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
-// +kubebuilder:printcolumn:name="SECRET-NAME",type="string",JSONPath=".spec.credentials.secretRef.name",priority=1
+// +kubebuilder:printcolumn:name="CONFIG-NAME",type="string",JSONPath=".spec.providerConfigRef.name"
+// +kubebuilder:printcolumn:name="RESOURCE-KIND",type="string",JSONPath=".spec.resourceRef.kind"
+// +kubebuilder:printcolumn:name="RESOURCE-NAME",type="string",JSONPath=".spec.resourceRef.name"
 // +kubebuilder:resource:scope=Cluster,categories={crossplane,providerconfig,terraform}
 
 // A ProviderConfigUsage indicates that a resource is using a ProviderConfig.
@@ -135,3 +427,82 @@ type ProviderConfigUsageList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []ProviderConfigUsage `json:"items"`
 }
+
+// ProviderConfigGroupKind is the GroupKind for the ProviderConfig resource.
+var ProviderConfigGroupKind = schema.GroupKind{
+	Group: Group,
+	Kind:  "ProviderConfig",
+}
+
+// ProviderConfigGroupVersionKind is the GroupVersionKind for the
+// ProviderConfig resource.
+var ProviderConfigGroupVersionKind = schema.GroupVersionKind{
+	Group:   Group,
+	Version: Version,
+	Kind:    "ProviderConfig",
+}
+
+// ProviderConfigUsageGroupVersionKind is the GroupVersionKind for the
+// ProviderConfigUsage resource.
+var ProviderConfigUsageGroupVersionKind = schema.GroupVersionKind{
+	Group:   Group,
+	Version: Version,
+	Kind:    "ProviderConfigUsage",
+}
+
+// ProviderConfigUsageListGroupVersionKind is the GroupVersionKind for the
+// ProviderConfigUsageList resource.
+var ProviderConfigUsageListGroupVersionKind = schema.GroupVersionKind{
+	Group:   Group,
+	Version: Version,
+	Kind:    "ProviderConfigUsageList",
+}
+
+// GetCondition of this ProviderConfig.
+func (pc *ProviderConfig) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
+	return pc.Status.GetCondition(ct)
+}
+
+// SetConditions of this ProviderConfig.
+func (pc *ProviderConfig) SetConditions(c ...xpv1.Condition) {
+	pc.Status.SetConditions(c...)
+}
+
+// GetUsers of this ProviderConfig.
+func (pc *ProviderConfig) GetUsers() int64 {
+	return pc.Status.Users
+}
+
+// SetUsers of this ProviderConfig.
+func (pc *ProviderConfig) SetUsers(i int64) {
+	pc.Status.Users = i
+}
+
+// GetProviderConfigReference of this ProviderConfigUsage.
+func (pcu *ProviderConfigUsage) GetProviderConfigReference() xpv1.Reference {
+	return pcu.Spec.ProviderConfigRef
+}
+
+// SetProviderConfigReference of this ProviderConfigUsage.
+func (pcu *ProviderConfigUsage) SetProviderConfigReference(r xpv1.Reference) {
+	pcu.Spec.ProviderConfigRef = r
+}
+
+// GetResourceReference of this ProviderConfigUsage.
+func (pcu *ProviderConfigUsage) GetResourceReference() xpv1.TypedReference {
+	return pcu.Spec.ResourceRef
+}
+
+// SetResourceReference of this ProviderConfigUsage.
+func (pcu *ProviderConfigUsage) SetResourceReference(r xpv1.TypedReference) {
+	pcu.Spec.ResourceRef = r
+}
+
+// GetItems of this ProviderConfigUsageList.
+func (l *ProviderConfigUsageList) GetItems() []resource.ProviderConfigUsage {
+	items := make([]resource.ProviderConfigUsage, len(l.Items))
+	for i := range l.Items {
+		items[i] = &l.Items[i]
+	}
+	return items
+}
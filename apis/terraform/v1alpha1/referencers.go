@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"slices"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reference"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TerraformOutput returns an ExtractValueFn that extracts the named output
+// from a Terraform resource.
+func TerraformOutput(name string) reference.ExtractValueFn {
+	return func(mg resource.Managed) string {
+		tf, ok := mg.(*Terraform)
+		if !ok {
+			return ""
+		}
+		return tf.Status.AtProvider.Outputs[name]
+	}
+}
+
+// errSensitiveOutput is returned when a variable references a sensitive
+// output. Its value is never recorded in status, so would never resolve.
+const errSensitiveOutput = "output %q of Terraform %s is sensitive, so cannot be referenced; use a secretKeyRef to its connection secret instead"
+
+// ResolveReferences of this Terraform to the outputs of other Terraform
+// resources. Outputs change, so they are resolved again on every reconcile
+// rather than cached.
+func (mg *Terraform) ResolveReferences(ctx context.Context, c client.Reader) error {
+	// Keep the values last resolved while the resource is being deleted, so
+	// that it can be destroyed with them.
+	if meta.WasDeleted(mg) {
+		return nil
+	}
+
+	r := reference.NewAPIResolver(c, mg)
+
+	for i := range mg.Spec.ForProvider.VariablesFrom {
+		v := &mg.Spec.ForProvider.VariablesFrom[i]
+		if v.TerraformRef == nil && v.TerraformSelector == nil {
+			continue
+		}
+		// Note the referenced resource if the output is sensitive, so that
+		// resolution fails with an error that says so, rather than waiting
+		// for a value that will never be published.
+		sensitive := ""
+		extract := func(mg resource.Managed) string {
+			if tf, ok := mg.(*Terraform); ok && slices.Contains(tf.Status.AtProvider.SensitiveOutputs, v.Output) {
+				sensitive = tf.GetName()
+			}
+			return TerraformOutput(v.Output)(mg)
+		}
+		rsp, err := r.Resolve(ctx, reference.ResolutionRequest{
+			Reference: v.TerraformRef,
+			Selector:  v.TerraformSelector,
+			To:        reference.To{Managed: &Terraform{}, List: &TerraformList{}},
+			Extract:   extract,
+		})
+		if sensitive != "" {
+			return errors.Wrapf(errors.Errorf(errSensitiveOutput, v.Output, sensitive), "spec.forProvider.variablesFrom[%d]", i)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "spec.forProvider.variablesFrom[%d]", i)
+		}
+		v.Value = rsp.ResolvedValue
+		v.TerraformRef = rsp.ResolvedReference
+	}
+
+	return nil
+}
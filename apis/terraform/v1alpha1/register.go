@@ -43,4 +43,5 @@ func init() {
 	SchemeBuilder.Register(&Workspace{}, &WorkspaceList{})
 	SchemeBuilder.Register(&ProviderConfig{}, &ProviderConfigList{})
 	SchemeBuilder.Register(&ProviderConfigUsage{}, &ProviderConfigUsageList{})
+	SchemeBuilder.Register(&PlanPolicy{}, &PlanPolicyList{})
 }
@@ -22,29 +22,225 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
 )
 
+// AnnotationKeyCancel requests that any in-flight apply or destroy of a
+// Terraform resource be interrupted. While the annotation is present no new
+// apply or destroy will be started; remove it to resume reconciliation.
+const AnnotationKeyCancel = "terraform.crossplane.io/cancel"
+
+// AnnotationKeyForceUnlock requests that the state lock with the ID given as
+// the annotation's value be forcibly released. The ID must match the lock
+// reported in the resource's status.
+const AnnotationKeyForceUnlock = "terraform.crossplane.io/force-unlock"
+
+// AnnotationKeyMigrateState allows the backend and workspace of a Terraform
+// resource to be changed, which are otherwise immutable once its state has
+// been initialised. While the annotation is set to "true" the state is moved
+// from its previous location to the new one before anything is planned.
+const AnnotationKeyMigrateState = "terraform.crossplane.io/migrate-state"
+
+// DefaultWorkspace is the workspace Terraform uses when none is specified.
+const DefaultWorkspace = "default"
+
 // TerraformParameters are the configurable fields of a Terraform resource.
 type TerraformParameters struct {
-	// Configuration contains the raw Terraform configuration.
+	// Configuration is the Terraform configuration, either as a string of
+	// HCL, or as an object in Terraform's JSON configuration syntax.
 	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
 	Configuration runtime.RawExtension `json:"configuration"`
 
+	// Files are additional configuration files, keyed by their path relative
+	// to the working directory, e.g. variables.tf or modules/vpc/main.tf.
+	// Paths may not leave the working directory. Files removed from this
+	// map are removed from the working directory.
+	// +optional
+	Files map[string]string `json:"files,omitempty"`
+
 	// Variables is a map of Terraform variables.
 	// +optional
 	Variables map[string]string `json:"variables,omitempty"`
 
-	// Backend configuration for storing Terraform state.
+	// VariablesFrom sets variables to the outputs of other Terraform
+	// resources, to fields of other Kubernetes objects, or to keys of
+	// Secrets. A resource waits until every value it references exists, and
+	// is reconciled again whenever an output or field changes.
+	// +optional
+	VariablesFrom []VariableFrom `json:"variablesFrom,omitempty"`
+
+	// Backend configuration for storing Terraform state. Overrides the
+	// backend of the referenced Workspace, if any.
 	// +optional
 	Backend *BackendConfig `json:"backend,omitempty"`
 
-	// Workspace name for this Terraform configuration.
+	// Workspace name for this Terraform configuration. Overrides the name
+	// of the referenced Workspace, if any.
 	// +optional
 	Workspace string `json:"workspace,omitempty"`
 
+	// WorkspaceRef references a Workspace to inherit settings from: its
+	// name and backend unless workspace and backend are set, its variables
+	// that the configuration declares, unless set by variables or
+	// variablesFrom, and its environment, Terraform version and working
+	// directory. The resource is reconciled again whenever the Workspace
+	// changes.
+	// +optional
+	WorkspaceRef *xpv1.Reference `json:"workspaceRef,omitempty"`
+
+	// ProviderAliases configure aliased providers with the credentials of
+	// other ProviderConfigs, e.g. to manage resources in two regions or
+	// accounts. The providerConfigRef's credentials configure the default
+	// providers.
+	// +optional
+	ProviderAliases []ProviderAlias `json:"providerAliases,omitempty"`
+
 	// Source specifies the location of the Terraform module.
 	// +optional
 	Source *TerraformSource `json:"source,omitempty"`
+
+	// CancelGracePeriod is how long Terraform is given to stop gracefully,
+	// persisting its state and releasing its lock, after a cancellation is
+	// requested. Terraform is killed once it elapses. Defaults to 20s. The
+	// operations of a reconcile must finish within a minute, so it should
+	// be well under that.
+	// +optional
+	CancelGracePeriod *metav1.Duration `json:"cancelGracePeriod,omitempty"`
+
+	// LockTimeout is how long Terraform waits to acquire the state lock
+	// before failing. Defaults to 0s, i.e. fail immediately if the state is
+	// locked.
+	// +optional
+	LockTimeout *metav1.Duration `json:"lockTimeout,omitempty"`
+
+	// Imports adopts existing infrastructure into the Terraform state
+	// instead of creating it anew. Imports that would cause the imported
+	// resource to be replaced are blocked.
+	// +optional
+	Imports []TerraformImport `json:"imports,omitempty"`
+
+	// DeletionProtection prevents Terraform from destroying the managed
+	// infrastructure when this resource is deleted. Deletion is blocked
+	// until it is disabled.
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// MaxDestructiveChanges is the number of resources a plan may delete or
+	// replace before it is blocked. A blocked plan is applied only once its
+	// digest, reported in status.atProvider.pendingDestructiveChanges, is set
+	// as AcknowledgeDestructiveChanges. Plans are never blocked if unset.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDestructiveChanges *int `json:"maxDestructiveChanges,omitempty"`
+
+	// AcknowledgeDestructiveChanges acknowledges the blocked plan with this
+	// digest, allowing it to be applied once.
+	// +optional
+	AcknowledgeDestructiveChanges string `json:"acknowledgeDestructiveChanges,omitempty"`
+}
+
+// A VariableFrom sets a variable to an output of another Terraform resource,
+// a field of another Kubernetes object, or a key of a Secret.
+type VariableFrom struct {
+	// Name of the variable.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Output of the referenced Terraform resource to set the variable to.
+	// Only non-sensitive outputs can be referenced.
+	// +optional
+	Output string `json:"output,omitempty"`
+
+	// TerraformRef references the Terraform resource whose output to use.
+	// +optional
+	TerraformRef *xpv1.Reference `json:"terraformRef,omitempty"`
+
+	// TerraformSelector selects the Terraform resource whose output to use.
+	// +optional
+	TerraformSelector *xpv1.Selector `json:"terraformSelector,omitempty"`
+
+	// FieldRef selects a field of a Kubernetes object to set the variable
+	// to. The object's kind must be allowed by the provider.
+	// +optional
+	FieldRef *ObjectFieldSelector `json:"fieldRef,omitempty"`
+
+	// SecretKeyRef selects a key of a Secret to set the variable to. The
+	// value is never stored in the resource, and never written to the
+	// working directory.
+	// +optional
+	SecretKeyRef *xpv1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+
+	// Value the variable was last resolved to. Values read from Secrets are
+	// not recorded.
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+// A ProviderAlias configures an aliased provider with the credentials of a
+// ProviderConfig. Resources select it with provider = <provider>.<alias>.
+type ProviderAlias struct {
+	// Provider is the local name of the provider: aws, azurerm, google or
+	// google-beta.
+	// +kubebuilder:validation:Enum=aws;azurerm;google;google-beta
+	Provider string `json:"provider"`
+
+	// Alias of the provider configuration.
+	Alias string `json:"alias"`
+
+	// Region of the provider configuration. Defaults to the region set by
+	// the ProviderConfig's credentials or environment, if any. Not
+	// supported by azurerm, whose resources set their own location.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// ProviderConfigRef references the ProviderConfig whose credentials
+	// the provider configuration uses.
+	ProviderConfigRef xpv1.Reference `json:"providerConfigRef"`
+}
+
+// An ObjectFieldSelector selects a field of a Kubernetes object.
+type ObjectFieldSelector struct {
+	// APIVersion of the object, e.g. v1 or s3.aws.upbound.io/v1beta1.
+	// +kubebuilder:validation:Required
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the object, e.g. Service or Bucket.
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+
+	// Name of the object.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the object. Omit for cluster scoped objects.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// FieldPath is a JSONPath expression selecting the field, e.g.
+	// .status.atProvider.arn or {.spec.clusterIP}. Fields that aren't strings
+	// are encoded as JSON.
+	// +kubebuilder:validation:Required
+	FieldPath string `json:"fieldPath"`
+}
+
+// GroupVersionKind of the selected object.
+func (s *ObjectFieldSelector) GroupVersionKind() schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(s.APIVersion, s.Kind)
+}
+
+// TerraformImport identifies an existing resource to import.
+type TerraformImport struct {
+	// Address of the resource in the configuration, e.g.
+	// aws_s3_bucket.example.
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+
+	// ID of the existing resource, in the format its Terraform provider
+	// expects for import.
+	// +kubebuilder:validation:Required
+	ID string `json:"id"`
 }
 
 // BackendConfig represents Terraform backend configuration.
@@ -105,6 +301,11 @@ type TerraformObservation struct {
 	// +optional
 	Outputs map[string]string `json:"outputs,omitempty"`
 
+	// SensitiveOutputs are the names of the outputs that are sensitive.
+	// Their values are only published as connection details.
+	// +optional
+	SensitiveOutputs []string `json:"sensitiveOutputs,omitempty"`
+
 	// State of the Terraform execution.
 	// +optional
 	State string `json:"state,omitempty"`
@@ -120,6 +321,286 @@ type TerraformObservation struct {
 	// DestroyJobName is the name of the job that destroys the Terraform resources.
 	// +optional
 	DestroyJobName string `json:"destroyJobName,omitempty"`
+
+	// LastFailure describes the most recent failed Terraform operation, and
+	// when it will next be retried.
+	// +optional
+	LastFailure *TerraformFailure `json:"lastFailure,omitempty"`
+
+	// StateLock describes a state lock that prevented the last Terraform
+	// operation from running.
+	// +optional
+	StateLock *StateLockInfo `json:"stateLock,omitempty"`
+
+	// Imports reports the status of each requested import.
+	// +optional
+	Imports []ImportStatus `json:"imports,omitempty"`
+
+	// PendingDestructiveChanges describes a plan that was blocked because it
+	// deletes or replaces too many resources.
+	// +optional
+	PendingDestructiveChanges *DestructiveChanges `json:"pendingDestructiveChanges,omitempty"`
+
+	// AppliedDestructiveChanges is the digest of the last acknowledged
+	// destructive changes that were applied. The digests of later plans
+	// depend on it, so an acknowledgement is spent once its plan is applied.
+	// +optional
+	AppliedDestructiveChanges string `json:"appliedDestructiveChanges,omitempty"`
+
+	// Interface of the root module: the variables it declares, the outputs
+	// it produces and the providers it requires.
+	// +optional
+	Interface *ModuleInterface `json:"interface,omitempty"`
+
+	// UndeclaredVariables are the variables set that the root module doesn't
+	// declare. Terraform ignores them, but they are likely a typo, or left
+	// over from a variable that was removed.
+	// +optional
+	UndeclaredVariables []string `json:"undeclaredVariables,omitempty"`
+
+	// SecretAudit is the result of checking the working directory for the
+	// values of sensitive variables after the last run. It is only set if
+	// the resource has sensitive variables.
+	// +optional
+	SecretAudit *SecretAudit `json:"secretAudit,omitempty"`
+
+	// StateLocation is where the state was last initialised. Changing the
+	// backend or workspace requires the state to be migrated from here.
+	// +optional
+	StateLocation *StateLocation `json:"stateLocation,omitempty"`
+
+	// PolicyViolations lists the PlanPolicy rules the last plan violated.
+	// +optional
+	PolicyViolations []PolicyViolation `json:"policyViolations,omitempty"`
+
+	// CredentialLeases are the leases on the credentials read from Vault
+	// for the last run. They're revoked once it finishes.
+	// +optional
+	CredentialLeases []CredentialLease `json:"credentialLeases,omitempty"`
+}
+
+// A ModuleInterface describes what a Terraform module expects and produces.
+type ModuleInterface struct {
+	// Variables declared by the module.
+	// +optional
+	Variables []ModuleVariable `json:"variables,omitempty"`
+
+	// Outputs declared by the module.
+	// +optional
+	Outputs []ModuleOutput `json:"outputs,omitempty"`
+
+	// RequiredProviders of the module.
+	// +optional
+	RequiredProviders []RequiredProvider `json:"requiredProviders,omitempty"`
+}
+
+// A ModuleVariable is a variable declared by a Terraform module.
+type ModuleVariable struct {
+	// Name of the variable.
+	Name string `json:"name"`
+
+	// Type constraint of the variable, e.g. string or list(string). Any
+	// type is accepted if unset.
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Required is true if the variable has no default, and so must be set.
+	Required bool `json:"required"`
+
+	// Sensitive is true if the variable's value is sensitive.
+	// +optional
+	Sensitive bool `json:"sensitive,omitempty"`
+
+	// Description of the variable.
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// A ModuleOutput is an output declared by a Terraform module.
+type ModuleOutput struct {
+	// Name of the output.
+	Name string `json:"name"`
+
+	// Sensitive is true if the output's value is sensitive.
+	// +optional
+	Sensitive bool `json:"sensitive,omitempty"`
+
+	// Description of the output.
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// A RequiredProvider is a provider required by a Terraform module.
+type RequiredProvider struct {
+	// Name the module uses for the provider, e.g. aws.
+	Name string `json:"name"`
+
+	// Source address of the provider, e.g. hashicorp/aws.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// Version constraint of the provider, e.g. ~> 5.0.
+	// +optional
+	Version string `json:"version,omitempty"`
+}
+
+// A SecretAudit reports where the values of sensitive variables were found
+// in the working directory.
+type SecretAudit struct {
+	// Clean is true if no values of sensitive variables were found outside
+	// Terraform state.
+	Clean bool `json:"clean"`
+
+	// Findings are the files that held values of sensitive variables. They
+	// are only reported, never removed.
+	// +optional
+	Findings []string `json:"findings,omitempty"`
+}
+
+// A StateLocation identifies where Terraform state is stored.
+type StateLocation struct {
+	// Backend storing the state. The local backend is used if unset.
+	// +optional
+	Backend *BackendConfig `json:"backend,omitempty"`
+
+	// Workspace the state belongs to.
+	Workspace string `json:"workspace"`
+}
+
+// StateLocation returns the location of the state the supplied parameters
+// configure.
+func (p *TerraformParameters) StateLocation() StateLocation {
+	l := StateLocation{Backend: p.Backend.DeepCopy(), Workspace: p.Workspace}
+	if l.Workspace == "" {
+		l.Workspace = DefaultWorkspace
+	}
+	if l.Backend != nil && len(l.Backend.Configuration) == 0 {
+		l.Backend.Configuration = nil
+	}
+	return l
+}
+
+// Equal returns true if the supplied location is the same as this one.
+func (l StateLocation) Equal(o StateLocation) bool {
+	if l.Workspace != o.Workspace {
+		return false
+	}
+	if l.Backend == nil || o.Backend == nil {
+		return l.Backend == nil && o.Backend == nil
+	}
+	if l.Backend.Type != o.Backend.Type || len(l.Backend.Configuration) != len(o.Backend.Configuration) {
+		return false
+	}
+	for k, v := range l.Backend.Configuration {
+		if ov, ok := o.Backend.Configuration[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// A PolicyViolation is a PlanPolicy rule a plan violated.
+type PolicyViolation struct {
+	// Policy is the name of the violated PlanPolicy.
+	Policy string `json:"policy"`
+
+	// Rule is the name of the violated rule.
+	Rule string `json:"rule"`
+
+	// EnforcementAction of the violated PlanPolicy.
+	EnforcementAction string `json:"enforcementAction"`
+
+	// Message describing the violation.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DestructiveChanges describes the resources a plan deletes or replaces.
+type DestructiveChanges struct {
+	// Digest identifies the set of destructive changes. Set it as
+	// spec.forProvider.acknowledgeDestructiveChanges to apply them.
+	Digest string `json:"digest"`
+
+	// Deletes are the addresses of the resources the plan deletes.
+	// +optional
+	Deletes []string `json:"deletes,omitempty"`
+
+	// Replaces are the addresses of the resources the plan replaces.
+	// +optional
+	Replaces []string `json:"replaces,omitempty"`
+}
+
+// ImportStatus reports the status of an import.
+type ImportStatus struct {
+	// Address of the imported resource.
+	Address string `json:"address"`
+
+	// ID of the imported resource.
+	ID string `json:"id"`
+
+	// Status of the import.
+	// +kubebuilder:validation:Enum=Pending;Imported;Blocked
+	Status string `json:"status"`
+
+	// Message explains why an import is blocked.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Import statuses.
+const (
+	ImportPending  = "Pending"
+	ImportImported = "Imported"
+	ImportBlocked  = "Blocked"
+)
+
+// StateLockInfo describes a Terraform state lock.
+type StateLockInfo struct {
+	// ID of the lock. Use it as the value of the
+	// terraform.crossplane.io/force-unlock annotation to release the lock.
+	ID string `json:"id"`
+
+	// Path of the locked state.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Operation that holds the lock.
+	// +optional
+	Operation string `json:"operation,omitempty"`
+
+	// Who holds the lock.
+	// +optional
+	Who string `json:"who,omitempty"`
+
+	// Created is when the lock was acquired.
+	// +optional
+	Created *metav1.Time `json:"created,omitempty"`
+}
+
+// TerraformFailure describes a failed Terraform operation.
+type TerraformFailure struct {
+	// Class of the failure, which determines how it is retried.
+	// +kubebuilder:validation:Enum=StateLocked;Throttled;AuthFailure;ConfigError;NetworkError;ImportBlocked;DeletionProtected;DestructiveChanges;PolicyViolation;MigrationRequired;InvalidVariables;UnknownError
+	Class xpv1.ConditionReason `json:"class"`
+
+	// Attempts is the number of consecutive failures of this class.
+	Attempts int `json:"attempts"`
+
+	// ObservedGeneration is the generation of the resource that failed.
+	// ConfigError failures are not retried until the generation changes.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ObservedWorkspaceGeneration is the generation of the referenced
+	// Workspace when the resource failed. A change to the Workspace counts
+	// as a change to the resource's spec.
+	// +optional
+	ObservedWorkspaceGeneration int64 `json:"observedWorkspaceGeneration,omitempty"`
+
+	// RetryAfter is the earliest time at which the operation will be
+	// retried.
+	// +optional
+	RetryAfter *metav1.Time `json:"retryAfter,omitempty"`
 }
 
 // A TerraformSpec defines the desired state of a Terraform resource.
@@ -160,6 +641,15 @@ type TerraformList struct {
 	Items           []Terraform `json:"items"`
 }
 
+// GetItems of this TerraformList.
+func (l *TerraformList) GetItems() []resource.Managed {
+	items := make([]resource.Managed, len(l.Items))
+	for i := range l.Items {
+		items[i] = &l.Items[i]
+	}
+	return items
+}
+
 // GetCondition of this Terraform.
 func (mg *Terraform) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
 	return mg.Status.GetCondition(ct)
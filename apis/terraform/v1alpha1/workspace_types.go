@@ -23,37 +23,84 @@ import (
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 )
 
+// AnnotationKeyRetryDestroy requests that the remote workspace of a deleted
+// Workspace be destroyed again after its destroy run with the ID given as the
+// annotation's value errored. Changing the Workspace's spec does too.
+const AnnotationKeyRetryDestroy = "terraform.crossplane.io/retry-destroy"
+
 // WorkspaceParameters are the configurable fields of a Workspace resource.
 type WorkspaceParameters struct {
 	// Name of the Terraform workspace.
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 
-	// Variables is a map of Terraform variables for this workspace.
+	// Backend storing the workspace's state. The local backend is used if
+	// unset, which keeps the workspace in the provider's own working
+	// directory. A Workspace that Terraform resources reference must set
+	// one, which they share.
+	// +optional
+	Backend *BackendConfig `json:"backend,omitempty"`
+
+	// Remote manages the workspace through the Terraform Cloud or Terraform
+	// Enterprise API rather than a backend. Its variables, environment and
+	// settings are synced to the remote workspace, where its runs execute.
+	// +optional
+	Remote *RemoteWorkspace `json:"remote,omitempty"`
+
+	// Variables is a map of Terraform variables for this workspace. They are
+	// synced to remote workspaces, and inherited by the Terraform resources
+	// referencing the Workspace that declare them.
 	// +optional
 	Variables map[string]string `json:"variables,omitempty"`
 
-	// Environment variables for this workspace.
+	// Environment variables for this workspace. They are passed to Terraform
+	// when managing the workspace on its backend, and synced to remote
+	// workspaces.
 	// +optional
 	Environment map[string]string `json:"environment,omitempty"`
 
-	// AutoApply determines if changes should be automatically applied.
+	// AutoApply determines if changes should be automatically applied. It is
+	// only used by remote workspaces, whose runs otherwise wait to be
+	// confirmed.
 	// +optional
 	AutoApply bool `json:"autoApply,omitempty"`
 
-	// AutoDestroy determines if the workspace should be automatically destroyed.
+	// AutoDestroy allows the workspace to be deleted while its state still
+	// manages resources, by destroying them first. The resources of a remote
+	// workspace are destroyed by a destroy run; those of any other workspace
+	// by terraform destroy, with providers configured by the environment.
 	// +optional
 	AutoDestroy bool `json:"autoDestroy,omitempty"`
 
-	// TerraformVersion specifies the Terraform version to use.
+	// TerraformVersion specifies the Terraform version remote runs use, and
+	// that Terraform resources referencing the Workspace require.
 	// +optional
 	TerraformVersion string `json:"terraformVersion,omitempty"`
 
-	// WorkingDirectory is the working directory for Terraform execution.
+	// WorkingDirectory is the directory of a remote workspace's configuration
+	// that runs execute in, and that Terraform resources referencing the
+	// Workspace run Terraform in.
 	// +optional
 	WorkingDirectory string `json:"workingDirectory,omitempty"`
 }
 
+// A RemoteWorkspace is managed through the Terraform Cloud or Terraform
+// Enterprise API.
+type RemoteWorkspace struct {
+	// Address of the API.
+	// +kubebuilder:default="https://app.terraform.io"
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Organization owning the workspace.
+	// +kubebuilder:validation:Required
+	Organization string `json:"organization"`
+
+	// TokenSecretRef selects the API token to manage the workspace with.
+	// +kubebuilder:validation:Required
+	TokenSecretRef xpv1.SecretKeySelector `json:"tokenSecretRef"`
+}
+
 // WorkspaceObservation are the observable fields of a Workspace resource.
 type WorkspaceObservation struct {
 	// ID of the workspace.
@@ -76,13 +123,25 @@ type WorkspaceObservation struct {
 	// +optional
 	ResourceCount int `json:"resourceCount,omitempty"`
 
-	// CreatedAt timestamp.
+	// StateSerial is the serial number of the workspace's state, which
+	// Terraform increments every time it writes the state.
+	// +optional
+	StateSerial int64 `json:"stateSerial,omitempty"`
+
+	// CreatedAt is when the workspace was created, or first observed if it
+	// already existed.
 	// +optional
 	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
 
-	// UpdatedAt timestamp.
+	// UpdatedAt is when the workspace's state was first observed at its
+	// current serial.
 	// +optional
 	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+
+	// CredentialLeases are the leases on the credentials read from Vault
+	// for the last run. They're revoked once it finishes.
+	// +optional
+	CredentialLeases []CredentialLease `json:"credentialLeases,omitempty"`
 }
 
 // WorkspaceRun contains information about a workspace run.
@@ -106,6 +165,11 @@ type WorkspaceRun struct {
 	// IsDestroy indicates if this is a destroy run.
 	// +optional
 	IsDestroy bool `json:"isDestroy,omitempty"`
+
+	// Generation of the Workspace resource when Crossplane queued the run,
+	// if it did.
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
 }
 
 // A WorkspaceSpec defines the desired state of a Workspace resource.
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/validation"
+)
+
+const (
+	errAdaptCredentials = "cannot adapt %s credentials"
+	errUnknownAdapter   = "unknown credentials adapter"
+	errNotJSONObject    = "credentials are not a JSON object of strings"
+	errMissingKeys      = "credentials have no %s"
+	errNotGCPKey        = "credentials are not a service account key"
+	errNotEnvVarName    = "%q is not a valid environment variable name"
+
+	awsCredentialsFile = "aws-credentials"
+	gcpCredentialsFile = "gcp-credentials.json"
+)
+
+// A credentialsAdapter translates credentials into the environment variables
+// and files a cloud's Terraform provider reads, adding them to c. Files are
+// written with c.writeFile, so that they are removed once Terraform has run.
+type credentialsAdapter func(c *credentials, a *v1alpha1.CredentialsAdapter, prefix string, data []byte) error
+
+// credentialsAdapters are the adapters of each type.
+var credentialsAdapters = map[v1alpha1.CredentialsAdapterType]credentialsAdapter{
+	v1alpha1.CredentialsAdapterAWS:    adaptAWS,
+	v1alpha1.CredentialsAdapterGCP:    adaptGCP,
+	v1alpha1.CredentialsAdapterAzure:  adaptAzure,
+	v1alpha1.CredentialsAdapterEnvMap: adaptEnvMap,
+}
+
+// adapt translates the supplied credentials with the supplied adapter.
+func (c *credentials) adapt(a *v1alpha1.CredentialsAdapter, prefix string, data []byte) error {
+	fn, ok := credentialsAdapters[a.Type]
+	if !ok {
+		return errors.Wrapf(errors.New(errUnknownAdapter), errAdaptCredentials, a.Type)
+	}
+	return errors.Wrapf(fn(c, a, prefix, data), errAdaptCredentials, a.Type)
+}
+
+// adaptAWS translates AWS access keys, or an AWS shared credentials file.
+func adaptAWS(c *credentials, a *v1alpha1.CredentialsAdapter, prefix string, data []byte) error {
+	if !isJSONObject(data) {
+		path, err := c.writeFile(prefix, awsCredentialsFile, data)
+		if err != nil {
+			return err
+		}
+		c.env["AWS_SHARED_CREDENTIALS_FILE"] = path
+		if a.Profile != "" {
+			c.env["AWS_PROFILE"] = a.Profile
+		}
+		return nil
+	}
+
+	keys, err := jsonStrings(data)
+	if err != nil {
+		return err
+	}
+	if err := requireKeys(keys, "aws_access_key_id", "aws_secret_access_key"); err != nil {
+		return err
+	}
+	c.env["AWS_ACCESS_KEY_ID"] = keys["aws_access_key_id"]
+	c.env["AWS_SECRET_ACCESS_KEY"] = keys["aws_secret_access_key"]
+	if v := keys["aws_session_token"]; v != "" {
+		c.env["AWS_SESSION_TOKEN"] = v
+	}
+	if v := keys["region"]; v != "" {
+		c.env["AWS_REGION"] = v
+		c.env["AWS_DEFAULT_REGION"] = v
+	}
+	return nil
+}
+
+// adaptGCP translates a Google Cloud service account key. Its project is
+// used unless the environment sets one.
+func adaptGCP(c *credentials, _ *v1alpha1.CredentialsAdapter, prefix string, data []byte) error {
+	key := struct {
+		Type      string `json:"type"`
+		ProjectID string `json:"project_id"`
+	}{}
+	if err := json.Unmarshal(data, &key); err != nil || key.Type == "" {
+		return errors.New(errNotGCPKey)
+	}
+	path, err := c.writeFile(prefix, gcpCredentialsFile, data)
+	if err != nil {
+		return err
+	}
+	c.env["GOOGLE_APPLICATION_CREDENTIALS"] = path
+	if _, ok := c.env["GOOGLE_PROJECT"]; !ok && key.ProjectID != "" {
+		c.env["GOOGLE_PROJECT"] = key.ProjectID
+	}
+	return nil
+}
+
+// adaptAzure translates the client secret of an Azure service principal. The
+// keys output by az ad sp create-for-rbac --sdk-auth, and their snake_case
+// forms, are understood.
+func adaptAzure(c *credentials, _ *v1alpha1.CredentialsAdapter, _ string, data []byte) error {
+	keys, err := jsonStrings(data)
+	if err != nil {
+		return err
+	}
+	for _, k := range []struct {
+		env, name, snake string
+		required         bool
+	}{
+		{env: "ARM_CLIENT_ID", name: "clientId", snake: "client_id", required: true},
+		{env: "ARM_CLIENT_SECRET", name: "clientSecret", snake: "client_secret", required: true},
+		{env: "ARM_TENANT_ID", name: "tenantId", snake: "tenant_id", required: true},
+		{env: "ARM_SUBSCRIPTION_ID", name: "subscriptionId", snake: "subscription_id"},
+	} {
+		v := keys[k.name]
+		if v == "" {
+			v = keys[k.snake]
+		}
+		switch {
+		case v != "":
+			c.env[k.env] = v
+		case k.required:
+			return errors.Errorf(errMissingKeys, k.name)
+		}
+	}
+	return nil
+}
+
+// adaptEnvMap translates a JSON object of environment variables.
+func adaptEnvMap(c *credentials, _ *v1alpha1.CredentialsAdapter, _ string, data []byte) error {
+	keys, err := jsonStrings(data)
+	if err != nil {
+		return err
+	}
+	for k, v := range keys {
+		if !validation.EnvVarName.MatchString(k) {
+			return errors.Errorf(errNotEnvVarName, k)
+		}
+		c.env[k] = v
+	}
+	return nil
+}
+
+func isJSONObject(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+// jsonStrings returns the supplied JSON object of strings.
+func jsonStrings(data []byte) (map[string]string, error) {
+	keys := map[string]string{}
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, errors.New(errNotJSONObject)
+	}
+	return keys, nil
+}
+
+// requireKeys returns an error if any of the named keys is missing.
+func requireKeys(keys map[string]string, names ...string) error {
+	for _, n := range names {
+		if keys[n] == "" {
+			return errors.Errorf(errMissingKeys, n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+func TestAdaptCredentials(t *testing.T) {
+	const (
+		awsFile = "[default]\naws_access_key_id = AKIA\naws_secret_access_key = secret\n"
+		gcpKey  = `{"type": "service_account", "project_id": "acme", "private_key": "key"}`
+	)
+
+	type want struct {
+		env   map[string]string
+		files map[string]string
+		err   bool
+	}
+	cases := map[string]struct {
+		reason  string
+		adapter v1alpha1.CredentialsAdapter
+		env     map[string]string
+		data    string
+		want    want
+	}{
+		"AWSKeys": {
+			reason:  "AWS access keys should be passed as environment variables.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterAWS},
+			data:    `{"aws_access_key_id": "AKIA", "aws_secret_access_key": "secret", "aws_session_token": "token", "region": "eu-west-1"}`,
+			want: want{env: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "AKIA",
+				"AWS_SECRET_ACCESS_KEY": "secret",
+				"AWS_SESSION_TOKEN":     "token",
+				"AWS_REGION":            "eu-west-1",
+				"AWS_DEFAULT_REGION":    "eu-west-1",
+			}},
+		},
+		"AWSMissingKeys": {
+			reason:  "AWS access keys without a secret key should be rejected.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterAWS},
+			data:    `{"aws_access_key_id": "AKIA"}`,
+			want:    want{env: map[string]string{}, err: true},
+		},
+		"AWSSharedCredentials": {
+			reason:  "An AWS shared credentials file should be written to a file, and its profile selected.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterAWS, Profile: "prod"},
+			data:    awsFile,
+			want: want{
+				env:   map[string]string{"AWS_SHARED_CREDENTIALS_FILE": awsCredentialsFile, "AWS_PROFILE": "prod"},
+				files: map[string]string{awsCredentialsFile: awsFile},
+			},
+		},
+		"GCP": {
+			reason:  "A Google Cloud service account key should be written to a file, and its project used.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterGCP},
+			data:    gcpKey,
+			want: want{
+				env:   map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": gcpCredentialsFile, "GOOGLE_PROJECT": "acme"},
+				files: map[string]string{gcpCredentialsFile: gcpKey},
+			},
+		},
+		"GCPProjectSet": {
+			reason:  "The project of a Google Cloud service account key should not override the environment's.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterGCP},
+			env:     map[string]string{"GOOGLE_PROJECT": "other"},
+			data:    gcpKey,
+			want: want{
+				env:   map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": gcpCredentialsFile, "GOOGLE_PROJECT": "other"},
+				files: map[string]string{gcpCredentialsFile: gcpKey},
+			},
+		},
+		"GCPNotKey": {
+			reason:  "Credentials that aren't a service account key should be rejected.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterGCP},
+			data:    `{"project_id": "acme"}`,
+			want:    want{env: map[string]string{}, err: true},
+		},
+		"Azure": {
+			reason:  "The keys output by the Azure CLI should be passed as environment variables.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterAzure},
+			data:    `{"clientId": "id", "clientSecret": "secret", "tenantId": "tenant", "subscriptionId": "sub"}`,
+			want: want{env: map[string]string{
+				"ARM_CLIENT_ID":       "id",
+				"ARM_CLIENT_SECRET":   "secret",
+				"ARM_TENANT_ID":       "tenant",
+				"ARM_SUBSCRIPTION_ID": "sub",
+			}},
+		},
+		"AzureSnakeCase": {
+			reason:  "The snake_case forms of the Azure keys should be understood, and the subscription is optional.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterAzure},
+			data:    `{"client_id": "id", "client_secret": "secret", "tenant_id": "tenant"}`,
+			want: want{env: map[string]string{
+				"ARM_CLIENT_ID":     "id",
+				"ARM_CLIENT_SECRET": "secret",
+				"ARM_TENANT_ID":     "tenant",
+			}},
+		},
+		"AzureMissingKeys": {
+			reason:  "Azure credentials without a tenant should be rejected.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterAzure},
+			data:    `{"clientId": "id", "clientSecret": "secret"}`,
+			want:    want{env: map[string]string{"ARM_CLIENT_ID": "id", "ARM_CLIENT_SECRET": "secret"}, err: true},
+		},
+		"EnvMap": {
+			reason:  "A JSON object of environment variables should be passed as is.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterEnvMap},
+			data:    `{"VAULT_TOKEN": "token", "TF_VAR_region": "eu-west-1"}`,
+			want:    want{env: map[string]string{"VAULT_TOKEN": "token", "TF_VAR_region": "eu-west-1"}},
+		},
+		"EnvMapInvalidName": {
+			reason:  "A key that isn't a valid environment variable name should be rejected.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterEnvMap},
+			data:    `{"NOT VALID": "x"}`,
+			want:    want{env: map[string]string{}, err: true},
+		},
+		"EnvMapNotStrings": {
+			reason:  "A JSON object of values other than strings should be rejected.",
+			adapter: v1alpha1.CredentialsAdapter{Type: v1alpha1.CredentialsAdapterEnvMap},
+			data:    `{"PORT": 8080}`,
+			want:    want{env: map[string]string{}, err: true},
+		},
+		"Unknown": {
+			reason:  "An unknown adapter should be rejected.",
+			adapter: v1alpha1.CredentialsAdapter{Type: "Oracle"},
+			data:    `{}`,
+			want:    want{env: map[string]string{}, err: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := &credentials{env: map[string]string{}, dir: dir}
+			for k, v := range tc.env {
+				c.env[k] = v
+			}
+
+			err := c.adapt(&tc.adapter, "test", []byte(tc.data))
+			if (err != nil) != tc.want.err {
+				t.Errorf("\n%s\nadapt(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+
+			// Files are expected by name, relative to the credentials'
+			// directory.
+			env := map[string]string{}
+			for k, v := range c.env {
+				if rel, err := filepath.Rel(dir, v); err == nil && filepath.IsAbs(v) {
+					v = rel
+				}
+				env[k] = v
+			}
+			if diff := cmp.Diff(tc.want.env, env); diff != "" {
+				t.Errorf("\n%s\nadapt(...): -want env, +got env:\n%s", tc.reason, diff)
+			}
+			for name, want := range tc.want.files {
+				got, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatalf("\n%s\nadapt(...): %v", tc.reason, err)
+				}
+				if diff := cmp.Diff(want, string(got)); diff != "" {
+					t.Errorf("\n%s\nadapt(...): -want %s, +got %s:\n%s", tc.reason, name, name, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errGetAliasCreds   = "cannot get credentials of provider %s.%s"
+	errUnknownProvider = "cannot configure aliases of provider %s"
+	errTrackAlias      = "cannot track ProviderConfig usage of provider %s.%s"
+	errListUsages      = "cannot list ProviderConfigUsages"
+	errDeleteAlias     = "cannot delete ProviderConfigUsage of removed provider alias"
+	errWriteProviders  = "cannot write provider configuration"
+
+	providersFile = "crossplane_providers.tf"
+
+	// labelKeyResourceUID is added to the ProviderConfigUsages of provider
+	// aliases to relate them to the Terraform resource that uses them.
+	labelKeyResourceUID = "terraform.crossplane.io/resource-uid"
+)
+
+// A providerArgument is an argument of a provider configuration, set from
+// the first of its environment variables that the credentials set. Secret
+// arguments are passed as sensitive variables rather than written to the
+// working directory. Arguments of a block are written in it, if any is set.
+type providerArgument struct {
+	name   string
+	env    []string
+	secret bool
+	list   bool
+	block  string
+}
+
+// providerArguments are the arguments each provider that may be aliased is
+// configured with, in the order they're written.
+var providerArguments = map[string][]providerArgument{
+	"aws": {
+		{name: "region", env: []string{"AWS_REGION", "AWS_DEFAULT_REGION"}},
+		{name: "profile", env: []string{"AWS_PROFILE"}},
+		{name: "shared_credentials_files", env: []string{"AWS_SHARED_CREDENTIALS_FILE"}, list: true},
+		{name: "access_key", env: []string{"AWS_ACCESS_KEY_ID"}, secret: true},
+		{name: "secret_key", env: []string{"AWS_SECRET_ACCESS_KEY"}, secret: true},
+		{name: "token", env: []string{"AWS_SESSION_TOKEN"}, secret: true},
+		{name: "role_arn", env: []string{"AWS_ROLE_ARN"}, block: "assume_role_with_web_identity"},
+		{name: "web_identity_token_file", env: []string{"AWS_WEB_IDENTITY_TOKEN_FILE"}, block: "assume_role_with_web_identity"},
+		{name: "session_name", env: []string{"AWS_ROLE_SESSION_NAME"}, block: "assume_role_with_web_identity"},
+	},
+	"azurerm": {
+		{name: "subscription_id", env: []string{"ARM_SUBSCRIPTION_ID"}},
+		{name: "tenant_id", env: []string{"ARM_TENANT_ID"}},
+		{name: "client_id", env: []string{"ARM_CLIENT_ID"}},
+		{name: "client_secret", env: []string{"ARM_CLIENT_SECRET"}, secret: true},
+		{name: "use_oidc", env: []string{"ARM_USE_OIDC"}},
+		{name: "oidc_token_file_path", env: []string{"ARM_OIDC_TOKEN_FILE_PATH"}},
+	},
+	"google":      googleArguments,
+	"google-beta": googleArguments,
+}
+
+var googleArguments = []providerArgument{
+	{name: "region", env: []string{"GOOGLE_REGION"}},
+	{name: "project", env: []string{"GOOGLE_PROJECT"}},
+	{name: "credentials", env: []string{"GOOGLE_APPLICATION_CREDENTIALS"}},
+	{name: "credentials", env: []string{"GOOGLE_CREDENTIALS"}, secret: true},
+}
+
+// A providerAlias is an aliased provider, and the credentials of its
+// ProviderConfig.
+type providerAlias struct {
+	v1alpha1.ProviderAlias
+	creds *credentials
+}
+
+// connectAliases returns the aliased providers of the supplied Terraform
+// resource, with the credentials of their ProviderConfigs.
+func connectAliases(ctx context.Context, kube client.Client, cr *v1alpha1.Terraform) ([]providerAlias, error) {
+	aliases := make([]providerAlias, 0, len(cr.Spec.ForProvider.ProviderAliases))
+	for _, a := range cr.Spec.ForProvider.ProviderAliases {
+		pc := &v1alpha1.ProviderConfig{}
+		err := kube.Get(ctx, types.NamespacedName{Name: a.ProviderConfigRef.Name}, pc)
+		var creds *credentials
+		if err == nil {
+			creds, err = newCredentials(ctx, kube, pc)
+		}
+		if err != nil {
+			closeAliases(aliases)
+			return nil, errors.Wrapf(err, errGetAliasCreds, a.Provider, a.Alias)
+		}
+		aliases = append(aliases, providerAlias{ProviderAlias: a, creds: creds})
+	}
+	return aliases, nil
+}
+
+// closeAliases removes any files the credentials of the supplied aliased
+// providers were written to.
+func closeAliases(aliases []providerAlias) {
+	for _, a := range aliases {
+		a.creds.close()
+	}
+}
+
+// writeProvidersConfig writes a provider block for each aliased provider,
+// configured with the credentials of its ProviderConfig. It returns the
+// values of the sensitive variables its secret arguments are set from.
+func (c *TerraformExternal) writeProvidersConfig() (map[string]string, error) {
+	path := filepath.Join(c.service.runDir(), providersFile)
+	if len(c.aliases) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, errWriteProviders)
+		}
+		return nil, nil
+	}
+
+	sensitive := map[string]string{}
+	var providers, variables strings.Builder
+	for _, a := range c.aliases {
+		args, ok := providerArguments[a.Provider]
+		if !ok {
+			return nil, errors.Errorf(errUnknownProvider, a.Provider)
+		}
+		providers.WriteString(fmt.Sprintf("provider %q {\n  alias = %s\n", a.Provider, hclString(a.Alias)))
+		set := map[string]bool{}
+		blocks := map[string]*strings.Builder{}
+		var order []string
+		if a.Region != "" {
+			providers.WriteString(fmt.Sprintf("  region = %s\n", hclString(a.Region)))
+			set["region"] = true
+		}
+		for _, arg := range args {
+			v := firstEnv(a.creds.env, arg.env)
+			if v == "" || set[arg.name] {
+				continue
+			}
+			set[arg.name] = true
+			value := hclString(v)
+			if arg.secret {
+				name := strings.ReplaceAll(fmt.Sprintf("crossplane_%s_%s_%s", a.Provider, a.Alias, arg.name), "-", "_")
+				variables.WriteString(fmt.Sprintf("variable %q {\n  type      = string\n  sensitive = true\n}\n\n", name))
+				sensitive[name] = v
+				value = "var." + name
+			}
+			if arg.list {
+				value = "[" + value + "]"
+			}
+			if arg.block == "" {
+				providers.WriteString(fmt.Sprintf("  %s = %s\n", arg.name, value))
+				continue
+			}
+			if blocks[arg.block] == nil {
+				blocks[arg.block] = &strings.Builder{}
+				order = append(order, arg.block)
+			}
+			blocks[arg.block].WriteString(fmt.Sprintf("    %s = %s\n", arg.name, value))
+		}
+		for _, b := range order {
+			providers.WriteString(fmt.Sprintf("  %s {\n%s  }\n", b, blocks[b].String()))
+		}
+		if a.Provider == "azurerm" {
+			providers.WriteString("  features {}\n")
+		}
+		providers.WriteString("}\n\n")
+	}
+	err := os.WriteFile(path, []byte(providers.String()+variables.String()), 0600)
+	return sensitive, errors.Wrap(err, errWriteProviders)
+}
+
+// firstEnv returns the value of the first of the named variables that is set.
+func firstEnv(env map[string]string, names []string) string {
+	for _, n := range names {
+		if v := env[n]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// trackAliases records that the supplied Terraform resource uses the
+// ProviderConfigs of its aliased providers, so that they can't be deleted
+// while it does. Usages of aliases that were since removed are deleted.
+func trackAliases(ctx context.Context, kube client.Client, cr *v1alpha1.Terraform) error {
+	want := map[string]bool{}
+	for i, a := range cr.Spec.ForProvider.ProviderAliases {
+		name := fmt.Sprintf("%s-alias-%d", cr.GetUID(), i)
+		want[name] = true
+
+		pcu := &v1alpha1.ProviderConfigUsage{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		err := kube.Get(ctx, types.NamespacedName{Name: name}, pcu)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrapf(err, errTrackAlias, a.Provider, a.Alias)
+		}
+		if pcu.GetProviderConfigReference().Name == a.ProviderConfigRef.Name && pcu.GetLabels()[labelKeyResourceUID] == string(cr.GetUID()) {
+			continue
+		}
+		pcu.SetLabels(map[string]string{
+			xpv1.LabelKeyProviderName: a.ProviderConfigRef.Name,
+			labelKeyResourceUID:       string(cr.GetUID()),
+		})
+		pcu.SetOwnerReferences([]metav1.OwnerReference{meta.AsController(meta.TypedReferenceTo(cr, v1alpha1.TerraformGroupVersionKind))})
+		pcu.SetProviderConfigReference(xpv1.Reference{Name: a.ProviderConfigRef.Name})
+		pcu.SetResourceReference(xpv1.TypedReference{
+			APIVersion: v1alpha1.TerraformGroupVersionKind.GroupVersion().String(),
+			Kind:       v1alpha1.TerraformGroupVersionKind.Kind,
+			Name:       cr.GetName(),
+		})
+		if pcu.GetResourceVersion() == "" {
+			err = kube.Create(ctx, pcu)
+		} else {
+			err = kube.Update(ctx, pcu)
+		}
+		if err != nil {
+			return errors.Wrapf(err, errTrackAlias, a.Provider, a.Alias)
+		}
+	}
+
+	l := &v1alpha1.ProviderConfigUsageList{}
+	if err := kube.List(ctx, l, client.MatchingLabels{labelKeyResourceUID: string(cr.GetUID())}); err != nil {
+		return errors.Wrap(err, errListUsages)
+	}
+	for i := range l.Items {
+		pcu := &l.Items[i]
+		owner := metav1.GetControllerOf(pcu)
+		if owner == nil || owner.UID != cr.GetUID() || want[pcu.GetName()] {
+			continue
+		}
+		if err := kube.Delete(ctx, pcu); err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrap(err, errDeleteAlias)
+		}
+	}
+	return nil
+}
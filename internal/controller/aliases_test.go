@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+func TestTrackAliases(t *testing.T) {
+	cr := &v1alpha1.Terraform{ObjectMeta: metav1.ObjectMeta{Name: "network", UID: "cr-uid"}}
+	other := &v1alpha1.Terraform{ObjectMeta: metav1.ObjectMeta{Name: "dns", UID: "other-uid"}}
+	alias := v1alpha1.ProviderAlias{Provider: "aws", Alias: "west", ProviderConfigRef: xpv1.Reference{Name: "west"}}
+
+	usage := func(owner *v1alpha1.Terraform, name, pc string, labels map[string]string) *v1alpha1.ProviderConfigUsage {
+		pcu := &v1alpha1.ProviderConfigUsage{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+		pcu.SetOwnerReferences([]metav1.OwnerReference{meta.AsController(meta.TypedReferenceTo(owner, v1alpha1.TerraformGroupVersionKind))})
+		pcu.SetProviderConfigReference(xpv1.Reference{Name: pc})
+		return pcu
+	}
+	labels := func(owner *v1alpha1.Terraform, pc string) map[string]string {
+		return map[string]string{xpv1.LabelKeyProviderName: pc, labelKeyResourceUID: string(owner.GetUID())}
+	}
+
+	cases := map[string]struct {
+		reason   string
+		aliases  []v1alpha1.ProviderAlias
+		existing []client.Object
+		want     map[string]map[string]string
+	}{
+		"Created": {
+			reason:  "A usage labelled with the resource's UID should be created for each alias.",
+			aliases: []v1alpha1.ProviderAlias{alias},
+			want:    map[string]map[string]string{"cr-uid-alias-0": labels(cr, "west")},
+		},
+		"Labelled": {
+			reason:   "An existing usage of an alias should be labelled with the resource's UID.",
+			aliases:  []v1alpha1.ProviderAlias{alias},
+			existing: []client.Object{usage(cr, "cr-uid-alias-0", "west", map[string]string{xpv1.LabelKeyProviderName: "west"})},
+			want:     map[string]map[string]string{"cr-uid-alias-0": labels(cr, "west")},
+		},
+		"Removed": {
+			reason: "Usages of removed aliases should be deleted, but not the resource's own usage nor those of other resources.",
+			existing: []client.Object{
+				usage(cr, "cr-uid", "default", map[string]string{xpv1.LabelKeyProviderName: "default"}),
+				usage(cr, "cr-uid-alias-0", "west", labels(cr, "west")),
+				usage(other, "other-uid-alias-0", "west", labels(other, "west")),
+			},
+			want: map[string]map[string]string{
+				"cr-uid":            {xpv1.LabelKeyProviderName: "default"},
+				"other-uid-alias-0": labels(other, "west"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := runtime.NewScheme()
+			if err := v1alpha1.AddToScheme(s); err != nil {
+				t.Fatal(err)
+			}
+			kube := fake.NewClientBuilder().WithScheme(s).WithObjects(tc.existing...).Build()
+			cr := cr.DeepCopy()
+			cr.Spec.ForProvider.ProviderAliases = tc.aliases
+
+			if err := trackAliases(context.Background(), kube, cr); err != nil {
+				t.Fatalf("\n%s\ntrackAliases(...): %v", tc.reason, err)
+			}
+			l := &v1alpha1.ProviderConfigUsageList{}
+			if err := kube.List(context.Background(), l); err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]map[string]string{}
+			for _, pcu := range l.Items {
+				got[pcu.GetName()] = pcu.GetLabels()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ntrackAliases(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
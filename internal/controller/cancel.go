@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errCancelled       = "operation cancelled by request; remove the " + v1alpha1.AnnotationKeyCancel + " annotation to resume"
+	errSetWaitDelay    = "cannot set Terraform cancellation grace period"
+	errPushErroredTF   = "cannot persist errored Terraform state"
+	errForceUnlockTF   = "cannot release Terraform state lock"
+	erroredStateFile   = "errored.tfstate"
+	cancelPollInterval = 5 * time.Second
+
+	// defaultCancelGracePeriod leaves time within the minute a reconcile's
+	// operations must finish in to clean up after Terraform is killed.
+	defaultCancelGracePeriod = 20 * time.Second
+
+	// cancelCleanupTimeout bounds the cleanup after an interrupted run. It
+	// runs once the run's context is done, so can't use it.
+	cancelCleanupTimeout = 30 * time.Second
+)
+
+// cancelRequested returns true if cancellation of in-flight Terraform
+// operations has been requested for the supplied resource.
+func cancelRequested(cr *v1alpha1.Terraform) bool {
+	_, ok := cr.GetAnnotations()[v1alpha1.AnnotationKeyCancel]
+	return ok
+}
+
+// cancelGracePeriod returns how long Terraform may take to stop gracefully
+// before it is killed.
+func cancelGracePeriod(cr *v1alpha1.Terraform) time.Duration {
+	if d := cr.Spec.ForProvider.CancelGracePeriod; d != nil && d.Duration > 0 {
+		return d.Duration
+	}
+	return defaultCancelGracePeriod
+}
+
+// runCancellable runs the supplied Terraform operation, interrupting it if
+// cancellation is requested while it is in flight. Terraform is first sent an
+// interrupt so it can persist its state and release its lock, and is killed
+// if it has not stopped once the grace period has elapsed.
+func (c *TerraformExternal) runCancellable(ctx context.Context, cr *v1alpha1.Terraform, tf *tfexec.Terraform, fn func(ctx context.Context) error) error {
+	if cancelRequested(cr) {
+		cr.SetConditions(v1alpha1.Cancelled("Cancellation requested before the operation started"))
+		return errors.New(errCancelled)
+	}
+	if cr.GetCondition(v1alpha1.TypeCancelled).Status == corev1.ConditionTrue {
+		cr.SetConditions(v1alpha1.NotCancelled())
+	}
+
+	grace := cancelGracePeriod(cr)
+	if err := tf.SetWaitDelay(grace); err != nil {
+		return errors.Wrap(err, errSetWaitDelay)
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	requested := make(chan struct{})
+	go c.watchCancel(runCtx, client.ObjectKeyFromObject(cr), requested, stop)
+
+	err := fn(runCtx)
+	if err == nil {
+		return nil
+	}
+
+	select {
+	case <-requested:
+	default:
+		return err
+	}
+
+	// The operation was interrupted on request. Make sure whatever Terraform
+	// managed to do is recorded in the backend, and that the lock does not
+	// outlive the run.
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), cancelCleanupTimeout)
+	defer cancel()
+	msg := "Terraform stopped gracefully after an interrupt"
+	if killed(err) {
+		msg = fmt.Sprintf("Terraform was killed after not stopping within %s", grace)
+		if uerr := c.releaseLocalLock(cleanupCtx, tf, c.stateLocation(cr)); uerr != nil {
+			msg = fmt.Sprintf("%s; %s: %v", msg, errForceUnlockTF, uerr)
+		}
+	}
+	if perr := c.persistErroredState(cleanupCtx, tf); perr != nil {
+		msg = fmt.Sprintf("%s; %v", msg, perr)
+	}
+	cr.SetConditions(v1alpha1.Cancelled(msg))
+	return errors.New(errCancelled)
+}
+
+// watchCancel polls the supplied Terraform resource until ctx is done,
+// closing requested and calling stop as soon as cancellation is requested.
+func (c *TerraformExternal) watchCancel(ctx context.Context, key client.ObjectKey, requested chan<- struct{}, stop context.CancelFunc) {
+	t := time.NewTicker(cancelPollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		cr := &v1alpha1.Terraform{}
+		if err := c.kube.Get(ctx, key, cr); err != nil {
+			continue
+		}
+		if cancelRequested(cr) {
+			close(requested)
+			stop()
+			return
+		}
+	}
+}
+
+// persistErroredState pushes any state Terraform could not write to its
+// backend before it stopped.
+func (c *TerraformExternal) persistErroredState(ctx context.Context, tf *tfexec.Terraform) error {
+	path := filepath.Join(c.service.runDir(), erroredStateFile)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	if err := tf.StatePush(ctx, path); err != nil {
+		return errors.Wrap(err, errPushErroredTF)
+	}
+	return errors.Wrap(os.Remove(path), errPushErroredTF)
+}
+
+// releaseLocalLock releases a lock left behind on the local backend by a
+// killed Terraform process.
+func (c *TerraformExternal) releaseLocalLock(ctx context.Context, tf *tfexec.Terraform, l v1alpha1.StateLocation) error {
+	path := c.service.localLockPath(l)
+	if path == "" {
+		// The lock is held by a remote backend, which will expire it or
+		// must be unlocked explicitly.
+		return nil
+	}
+	id, err := readLocalLock(path)
+	if err != nil || id == "" {
+		return err
+	}
+	return tf.ForceUnlock(ctx, id)
+}
+
+// readLocalLock returns the ID of the lock recorded in the supplied lock info
+// file of the local backend, or "" if no lock is held.
+func readLocalLock(path string) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // The path is resolved from the state location.
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	info := struct {
+		ID string `json:"ID"`
+	}{}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return "", err
+	}
+	return info.ID, nil
+}
+
+// killed returns true if err indicates the Terraform process was killed.
+func killed(err error) bool {
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return false
+	}
+	ws, ok := ee.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && ws.Signal() == syscall.SIGKILL
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+func TestLocalLockPath(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		dir    string
+		l      v1alpha1.StateLocation
+		want   string
+	}{
+		"DefaultWorkspace": {
+			reason: "The lock of the default workspace should be next to the state in the directory Terraform runs in.",
+			l:      v1alpha1.StateLocation{Workspace: v1alpha1.DefaultWorkspace},
+			want:   "/work/.terraform.tfstate.lock.info",
+		},
+		"OtherWorkspace": {
+			reason: "The lock of another workspace should be next to its state under terraform.tfstate.d.",
+			l:      v1alpha1.StateLocation{Workspace: "staging"},
+			want:   "/work/terraform.tfstate.d/staging/.terraform.tfstate.lock.info",
+		},
+		"WorkingDirectory": {
+			reason: "The lock of local state kept at the top of the working directory should be there, not where Terraform runs.",
+			dir:    "envs/prod",
+			l:      v1alpha1.StateLocation{Workspace: v1alpha1.DefaultWorkspace},
+			want:   "/work/.terraform.tfstate.lock.info",
+		},
+		"WorkingDirectoryOtherWorkspace": {
+			reason: "The lock of another workspace kept at the top of the working directory should be there too.",
+			dir:    "envs/prod",
+			l:      v1alpha1.StateLocation{Workspace: "staging"},
+			want:   "/work/terraform.tfstate.d/staging/.terraform.tfstate.lock.info",
+		},
+		"LocalPath": {
+			reason: "The lock of a local backend with a relative path should be next to it, relative to where Terraform runs.",
+			l: v1alpha1.StateLocation{Workspace: v1alpha1.DefaultWorkspace, Backend: &v1alpha1.BackendConfig{
+				Type:          "local",
+				Configuration: map[string]string{"path": "state/network.tfstate"},
+			}},
+			want: "/work/state/.network.tfstate.lock.info",
+		},
+		"RemoteBackend": {
+			reason: "A remote backend keeps its own lock.",
+			l: v1alpha1.StateLocation{Workspace: v1alpha1.DefaultWorkspace, Backend: &v1alpha1.BackendConfig{
+				Type:          "s3",
+				Configuration: map[string]string{"bucket": "state"},
+			}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &TerraformService{workDir: "/work", dir: tc.dir}
+			if diff := cmp.Diff(tc.want, s.localLockPath(tc.l)); diff != "" {
+				t.Errorf("\n%s\nlocalLockPath(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReadLocalLock(t *testing.T) {
+	type want struct {
+		id  string
+		err bool
+	}
+	cases := map[string]struct {
+		reason string
+		data   *string
+		want   want
+	}{
+		"Locked": {
+			reason: "The ID of the recorded lock should be returned.",
+			data:   ptr(`{"ID":"8f9a3c2e","Operation":"OperationTypeApply","Who":"root@provider"}`),
+			want:   want{id: "8f9a3c2e"},
+		},
+		"NotLocked": {
+			reason: "No lock should be returned if no lock info file exists.",
+		},
+		"Corrupt": {
+			reason: "A lock info file that isn't JSON should be an error.",
+			data:   ptr("not json"),
+			want:   want{err: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".terraform.tfstate.lock.info")
+			if tc.data != nil {
+				if err := os.WriteFile(path, []byte(*tc.data), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			id, err := readLocalLock(path)
+			if (err != nil) != tc.want.err {
+				t.Errorf("\n%s\nreadLocalLock(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if diff := cmp.Diff(tc.want.id, id); diff != "" {
+				t.Errorf("\n%s\nreadLocalLock(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errCredentialsEnv   = "credentials environment variable %s is not set"
+	errCredentialsFile  = "cannot read credentials file"
+	errCredentialsEmpty = "credentials are empty"
+	errWriteCredentials = "cannot write credentials file"
+
+	credentialsFile = "credentials"
+)
+
+// credentials are the environment Terraform is run with to use the
+// credentials of a ProviderConfig, and the in-memory directory holding any
+// files it refers to.
+type credentials struct {
+	env map[string]string
+	dir string
+
+	// leases are the leases on any credentials read from Vault.
+	leases []v1alpha1.CredentialLease
+
+	// release releases what the credentials hold besides files, e.g. by
+	// revoking leases or stopping a token's refresh.
+	release []func()
+
+	// pending reads credentials that are issued afresh every time they're
+	// read, e.g. from Vault, when they are first needed. See load.
+	pending func(ctx context.Context) error
+}
+
+// connectCredentials returns the credentials of the ProviderConfig the
+// supplied managed resource uses.
+func connectCredentials(ctx context.Context, kube client.Client, mg resource.Managed) (*credentials, error) {
+	pc := &v1alpha1.ProviderConfig{}
+	if err := kube.Get(ctx, types.NamespacedName{Name: mg.GetProviderConfigReference().Name}, pc); err != nil {
+		return nil, errors.Wrap(err, errGetPC)
+	}
+	creds, err := newCredentials(ctx, kube, pc)
+	return creds, errors.Wrap(err, errGetCreds)
+}
+
+// newCredentials reads the credentials of the supplied ProviderConfig from
+// their source, or exchanges a service account token for them, and returns
+// the environment Terraform is run with to use them. Credentials read from
+// Vault are only read by load, as each read issues new ones. Any file
+// they're written to must be removed, and anything they hold released, by
+// calling close.
+func newCredentials(ctx context.Context, kube client.Client, pc *v1alpha1.ProviderConfig) (*credentials, error) {
+	c := &credentials{env: map[string]string{}}
+	for k, v := range pc.Spec.Environment {
+		c.env[k] = v
+	}
+	if w := pc.Spec.Credentials.WorkloadIdentity; w != nil && pc.Spec.Credentials.Source == xpv1.CredentialsSourceInjectedIdentity {
+		if err := c.federate(ctx, kube, pc.GetName(), w); err != nil {
+			c.close()
+			return nil, err
+		}
+		return c, nil
+	}
+	if v := pc.Spec.Credentials.Vault; v != nil && pc.Spec.Credentials.Source == v1alpha1.CredentialsSourceVault {
+		c.pending = func(ctx context.Context) error {
+			return c.readVault(ctx, kube, pc.GetName(), v)
+		}
+		return c, nil
+	}
+
+	data, err := readCredentials(ctx, kube, pc.Spec.Credentials)
+	if err != nil || data == nil {
+		return c, err
+	}
+	if v := pc.Spec.Credentials.EnvironmentVariable; v != "" {
+		c.env[v] = string(data)
+	}
+	if v := pc.Spec.Credentials.FileEnvironmentVariable; v != "" {
+		path, err := c.writeFile(pc.GetName(), credentialsFile, data)
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+		c.env[v] = path
+	}
+	if a := pc.Spec.Credentials.Adapter; a != nil {
+		if err := c.adapt(a, pc.GetName(), data); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// load reads any credentials that are only read when first needed. It does
+// nothing once they have been read.
+func (c *credentials) load(ctx context.Context) error {
+	if c == nil || c.pending == nil {
+		return nil
+	}
+	read := c.pending
+	c.pending = nil
+	return read(ctx)
+}
+
+// writeFile writes the supplied credentials to the named file, in a
+// directory of their own on the memory backed filesystem, and returns its
+// path.
+func (c *credentials) writeFile(prefix, name string, data []byte) (string, error) {
+	if c.dir == "" {
+		if fi, err := os.Stat(memoryDir); err != nil || !fi.IsDir() {
+			return "", errors.Wrap(errors.New(errNoMemoryFS), errWriteCredentials)
+		}
+		dir, err := os.MkdirTemp(memoryDir, "credentials-"+prefix+"-")
+		if err != nil {
+			return "", errors.Wrap(err, errWriteCredentials)
+		}
+		c.dir = dir
+	}
+	path := filepath.Join(c.dir, name)
+	return path, errors.Wrap(os.WriteFile(path, data, 0600), errWriteCredentials)
+}
+
+// close releases what the credentials hold, and removes any files they were
+// written to.
+func (c *credentials) close() {
+	if c == nil {
+		return
+	}
+	for i := len(c.release) - 1; i >= 0; i-- {
+		c.release[i]()
+	}
+	c.release = nil
+	if c.dir == "" {
+		return
+	}
+	_ = os.RemoveAll(c.dir)
+	c.dir = ""
+}
+
+// readCredentials reads the supplied credentials from their source. It
+// returns nil if the source has nothing to read: credentials of an injected
+// identity are found by Terraform itself.
+func readCredentials(ctx context.Context, kube client.Reader, c v1alpha1.ProviderCredentials) ([]byte, error) {
+	var data []byte
+	switch c.Source { //nolint:exhaustive // Other sources have nothing to read.
+	case xpv1.CredentialsSourceSecret:
+		if c.SecretRef == nil {
+			return nil, errors.New(errInvalidPC)
+		}
+		v, err := secretKey(ctx, kube, *c.SecretRef)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(v)
+	case xpv1.CredentialsSourceEnvironment:
+		if c.Env == nil {
+			return nil, errors.New(errInvalidPC)
+		}
+		v, ok := os.LookupEnv(c.Env.Name)
+		if !ok {
+			return nil, errors.Errorf(errCredentialsEnv, c.Env.Name)
+		}
+		data = []byte(v)
+	case xpv1.CredentialsSourceFilesystem:
+		if c.Fs == nil {
+			return nil, errors.New(errInvalidPC)
+		}
+		b, err := os.ReadFile(c.Fs.Path)
+		if err != nil {
+			return nil, errors.Wrap(err, errCredentialsFile)
+		}
+		data = b
+	default:
+		return nil, nil
+	}
+	if len(data) == 0 {
+		return nil, errors.New(errCredentialsEmpty)
+	}
+	return data, nil
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errResolveOutputs   = "cannot resolve outputs"
+	errSourceNotAllowed = "variables cannot be read from %s; the provider only allows %s"
+	errGetSource        = "cannot get %s %s"
+	errFieldPath        = "invalid field path %q"
+	errFieldNotFound    = "field %q of %s %s does not exist yet"
+	errWatchSource      = "cannot watch %s"
+	errUpdateResolved   = "cannot update resolved variables"
+)
+
+// A variableResolver resolves the variables of a Terraform resource that are
+// set to the outputs of other Terraform resources, or to fields of other
+// Kubernetes objects.
+type variableResolver struct {
+	client  client.Client
+	allowed map[schema.GroupVersionKind]bool
+	watches *sourceWatches
+}
+
+// ResolveReferences of the supplied Terraform resource, updating it if any
+// variable resolved to a new value.
+func (r *variableResolver) ResolveReferences(ctx context.Context, mg resource.Managed) error {
+	cr, ok := mg.(*v1alpha1.Terraform)
+	if !ok {
+		return errors.New(errNotTerraform)
+	}
+	existing := cr.DeepCopy()
+
+	if err := cr.ResolveReferences(ctx, r.client); err != nil {
+		return errors.Wrap(err, errResolveOutputs)
+	}
+	if err := r.resolveFields(ctx, cr); err != nil {
+		return err
+	}
+
+	if cmp.Equal(existing, cr) {
+		return nil
+	}
+	return errors.Wrap(r.client.Update(ctx, cr), errUpdateResolved)
+}
+
+// resolveFields resolves the variables of the supplied Terraform resource
+// that are set to fields of other Kubernetes objects.
+func (r *variableResolver) resolveFields(ctx context.Context, cr *v1alpha1.Terraform) error {
+	// Keep the values last resolved while the resource is being deleted, so
+	// that it can be destroyed with them.
+	if meta.WasDeleted(cr) {
+		return nil
+	}
+	for i := range cr.Spec.ForProvider.VariablesFrom {
+		v := &cr.Spec.ForProvider.VariablesFrom[i]
+		if v.FieldRef == nil {
+			continue
+		}
+		val, err := r.resolveField(ctx, v.FieldRef)
+		if err != nil {
+			return errors.Wrapf(err, "spec.forProvider.variablesFrom[%d]", i)
+		}
+		v.Value = val
+	}
+	return nil
+}
+
+// resolveField returns the value of the selected field, and makes sure
+// changes to the object holding it are watched.
+func (r *variableResolver) resolveField(ctx context.Context, s *v1alpha1.ObjectFieldSelector) (string, error) {
+	gvk := s.GroupVersionKind()
+	if !r.allowed[gvk] || isSecret(gvk) {
+		return "", errors.Errorf(errSourceNotAllowed, gvk, r.allowedKinds())
+	}
+	if err := r.watches.ensure(gvk); err != nil {
+		return "", err
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	nn := types.NamespacedName{Namespace: s.Namespace, Name: s.Name}
+	if err := r.client.Get(ctx, nn, u); err != nil {
+		return "", errors.Wrapf(err, errGetSource, gvk.Kind, nn)
+	}
+
+	jp := jsonpath.New("fieldPath")
+	if err := jp.Parse(jsonPathTemplate(s.FieldPath)); err != nil {
+		return "", errors.Wrapf(err, errFieldPath, s.FieldPath)
+	}
+	results, err := jp.FindResults(u.Object)
+	if err != nil || len(results) == 0 || len(results[0]) == 0 {
+		return "", errors.Errorf(errFieldNotFound, s.FieldPath, gvk.Kind, nn)
+	}
+
+	val := results[0][0].Interface()
+	if str, ok := val.(string); ok {
+		return str, nil
+	}
+	b, err := json.Marshal(val)
+	return string(b), errors.Wrapf(err, errFieldPath, s.FieldPath)
+}
+
+func (r *variableResolver) allowedKinds() string {
+	if len(r.allowed) == 0 {
+		return "none"
+	}
+	kinds := make([]string, 0, len(r.allowed))
+	for gvk := range r.allowed {
+		kinds = append(kinds, gvk.GroupVersion().String()+"/"+gvk.Kind)
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// isSecret returns true if the supplied kind is a Secret. Resolved values
+// are stored in the resource's spec, so they are never read from Secrets.
+func isSecret(gvk schema.GroupVersionKind) bool {
+	return gvk.Group == "" && gvk.Kind == "Secret"
+}
+
+// jsonPathTemplate returns the supplied field path as a JSONPath template,
+// accepting both .a.b and {.a.b}.
+func jsonPathTemplate(p string) string {
+	if strings.HasPrefix(p, "{") {
+		return p
+	}
+	if !strings.HasPrefix(p, ".") {
+		p = "." + p
+	}
+	return "{" + p + "}"
+}
+
+// sourceWatches starts watching kinds that variables are read from the first
+// time a variable is read from them, so that kinds that are allowed but not
+// installed don't stop the controller from starting.
+type sourceWatches struct {
+	mu      sync.Mutex
+	started map[schema.GroupVersionKind]bool
+
+	cache      cache.Cache
+	kube       client.Client
+	controller controller.Controller
+}
+
+// ensure the supplied kind is watched.
+func (w *sourceWatches) ensure(gvk schema.GroupVersionKind) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started[gvk] || w.controller == nil {
+		return nil
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := w.controller.Watch(source.Kind[client.Object](w.cache, u, enqueueFieldDependents(w.kube, gvk))); err != nil {
+		return errors.Wrapf(err, errWatchSource, gvk)
+	}
+	w.started[gvk] = true
+	return nil
+}
+
+// enqueueFieldDependents enqueues the Terraform resources with variables set
+// to a field of an object of the supplied kind.
+func enqueueFieldDependents(kube client.Client, gvk schema.GroupVersionKind) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		l := &v1alpha1.TerraformList{}
+		if err := kube.List(ctx, l); err != nil {
+			return nil
+		}
+		var reqs []reconcile.Request
+		for _, cr := range l.Items {
+			for _, v := range cr.Spec.ForProvider.VariablesFrom {
+				s := v.FieldRef
+				if s != nil && s.GroupVersionKind() == gvk && s.Name == obj.GetName() && s.Namespace == obj.GetNamespace() {
+					reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Name: cr.GetName()}})
+					break
+				}
+			}
+		}
+		return reqs
+	})
+}
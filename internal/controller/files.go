@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfconfig"
+)
+
+const (
+	errInvalidFilePath = "invalid configuration file path %q"
+	errWriteFile       = "cannot write configuration file %q"
+	errRemoveFile      = "cannot remove configuration file %q"
+	errWriteManifest   = "cannot write configuration file manifest"
+)
+
+// writeFiles writes the additional configuration files of the supplied
+// Terraform resource, and removes any it previously wrote that are no longer
+// part of its configuration.
+func (c *TerraformExternal) writeFiles(cr *v1alpha1.Terraform) error {
+	paths := make([]string, 0, len(cr.Spec.ForProvider.Files))
+	for p := range cr.Spec.ForProvider.Files {
+		// Paths are validated at admission too, but never trust them to
+		// stay within the working directory.
+		if err := tfconfig.ValidatePathIn(p, c.service.dir); err != nil {
+			return errors.Wrapf(err, errInvalidFilePath, p)
+		}
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	want := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		want[p] = true
+		dst := filepath.Join(c.service.workDir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			return errors.Wrapf(err, errWriteFile, p)
+		}
+		if err := os.WriteFile(dst, []byte(cr.Spec.ForProvider.Files[p]), 0600); err != nil {
+			return errors.Wrapf(err, errWriteFile, p)
+		}
+	}
+
+	for _, p := range c.writtenFiles() {
+		if want[p] || tfconfig.ValidatePath(p) != nil {
+			continue
+		}
+		if err := c.removeFile(p); err != nil {
+			return errors.Wrapf(err, errRemoveFile, p)
+		}
+	}
+
+	manifest := filepath.Join(c.service.workDir, tfconfig.ManifestFile)
+	return errors.Wrap(os.WriteFile(manifest, []byte(strings.Join(paths, "\n")), 0600), errWriteManifest)
+}
+
+// writtenFiles returns the paths of the configuration files written by the
+// last call to writeFiles.
+func (c *TerraformExternal) writtenFiles() []string {
+	b, err := os.ReadFile(filepath.Join(c.service.workDir, tfconfig.ManifestFile))
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	return strings.Split(string(b), "\n")
+}
+
+// removeFile removes the configuration file at the supplied path, and any
+// directories that are left empty by its removal.
+func (c *TerraformExternal) removeFile(p string) error {
+	if err := os.Remove(filepath.Join(c.service.workDir, filepath.FromSlash(p))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for dir := filepath.Dir(filepath.FromSlash(p)); dir != "."; dir = filepath.Dir(dir) {
+		// Remove fails on directories that aren't empty, which is where we
+		// want to stop.
+		if os.Remove(filepath.Join(c.service.workDir, dir)) != nil {
+			break
+		}
+	}
+	return nil
+}
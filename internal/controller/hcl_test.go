@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestHCLString(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		s      string
+		want   string
+	}{
+		"Plain": {
+			reason: "A plain string should only be quoted.",
+			s:      "us-east-1",
+			want:   `"us-east-1"`,
+		},
+		"Escapes": {
+			reason: "Quotes, backslashes and control characters should be escaped.",
+			s:      "a \"b\" \\c\n\r\t\x01",
+			want:   `"a \"b\" \\c\n\r\t\u0001"`,
+		},
+		"Templates": {
+			reason: "Template sequences should be escaped so that they're taken literally.",
+			s:      "${var.region} %{if true}",
+			want:   `"$${var.region} %%{if true}"`,
+		},
+		"NotTemplates": {
+			reason: "Dollar and percent signs that don't start a template sequence should be left alone.",
+			s:      "$5 100% $",
+			want:   `"$5 100% $"`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := hclString(tc.s)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nhclString(...): -want, +got:\n%s", tc.reason, diff)
+			}
+
+			// The literal must evaluate back to the string it was given.
+			expr, diags := hclsyntax.ParseExpression([]byte(got), "test.tf", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatalf("\n%s\nhclString(...): cannot parse %s: %s", tc.reason, got, diags)
+			}
+			v, diags := expr.Value(nil)
+			if diags.HasErrors() {
+				t.Fatalf("\n%s\nhclString(...): cannot evaluate %s: %s", tc.reason, got, diags)
+			}
+			if diff := cmp.Diff(tc.s, v.AsString()); diff != "" {
+				t.Errorf("\n%s\nhclString(...): -want, +got evaluated:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errFederate          = "cannot exchange service account token with %s"
+	errUnknownIdentity   = "unknown workload identity type"
+	errRequestToken      = "cannot request token for service account %s/%s"
+	errOwnServiceAccount = "cannot determine the provider's service account"
+	errWriteToken        = "cannot write service account token"
+	errNotJWT            = "token is not a JWT"
+	errDecodeToken       = "cannot decode token"
+	errNotServiceAccount = "token subject %q is not a service account"
+
+	// serviceAccountTokenPath is where the token of the provider's own
+	// service account is mounted, which names it.
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	identityTokenFile         = "token"
+	gcpExternalAccountFile    = "gcp-external-account.json"
+	defaultTokenExpiration    = int64(3600)
+	tokenRefreshRetryInterval = 30 * time.Second
+)
+
+// defaultAudiences are the audiences tokens are requested for by default.
+// The default audience of a GCP workload identity pool provider depends on
+// its name.
+var defaultAudiences = map[v1alpha1.WorkloadIdentityType]string{
+	v1alpha1.WorkloadIdentityAWS:   "sts.amazonaws.com",
+	v1alpha1.WorkloadIdentityAzure: "api://AzureADTokenExchange",
+}
+
+// An identityExchange sets the environment variables and writes the files a
+// cloud's Terraform provider reads to exchange the service account token
+// written to tokenPath for credentials.
+type identityExchange func(c *credentials, w *v1alpha1.WorkloadIdentity, name, tokenPath string) error
+
+// identityExchanges are the exchanges of each workload identity type.
+var identityExchanges = map[v1alpha1.WorkloadIdentityType]identityExchange{
+	v1alpha1.WorkloadIdentityAWS:   exchangeAWS,
+	v1alpha1.WorkloadIdentityGCP:   exchangeGCP,
+	v1alpha1.WorkloadIdentityAzure: exchangeAzure,
+}
+
+// A tokenRequester requests a service account token.
+type tokenRequester func(ctx context.Context) (*authenticationv1.TokenRequest, error)
+
+// federate requests a token for the service account of the supplied
+// workload identity, and sets up the credentials to exchange it for cloud
+// credentials. The token is refreshed until the credentials are closed:
+// Terraform's providers read it again whenever the cloud credentials they
+// exchanged it for expire, so runs may outlive a single token.
+func (c *credentials) federate(ctx context.Context, kube client.Client, name string, w *v1alpha1.WorkloadIdentity) error {
+	fn, ok := identityExchanges[w.Type]
+	if !ok {
+		return errors.Wrapf(errors.New(errUnknownIdentity), errFederate, w.Type)
+	}
+	audience := w.Audience
+	if audience == "" {
+		audience = defaultAudiences[w.Type]
+	}
+	if audience == "" && w.Type == v1alpha1.WorkloadIdentityGCP {
+		audience = "https://iam.googleapis.com/" + w.WorkloadIdentityProvider
+	}
+	expiration := defaultTokenExpiration
+	if w.ExpirationSeconds != nil {
+		expiration = *w.ExpirationSeconds
+	}
+	req, err := newTokenRequester(kube, w.ServiceAccountRef, audience, expiration)
+	if err != nil {
+		return errors.Wrapf(err, errFederate, w.Type)
+	}
+	tr, err := req(ctx)
+	if err != nil {
+		return errors.Wrapf(err, errFederate, w.Type)
+	}
+	path, err := c.writeFile(name, identityTokenFile, []byte(tr.Status.Token))
+	if err != nil {
+		return errors.Wrapf(err, errFederate, w.Type)
+	}
+	if err := fn(c, w, name, path); err != nil {
+		return errors.Wrapf(err, errFederate, w.Type)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		refreshToken(ctx, req, path, refreshAfter(tr))
+	}()
+	c.release = append(c.release, func() {
+		cancel()
+		<-done
+	})
+	return nil
+}
+
+// newTokenRequester returns a tokenRequester for the supplied service
+// account, or the provider's own if it is nil, and audience. Tokens without
+// an audience are for the Kubernetes API server.
+func newTokenRequester(kube client.Client, ref *v1alpha1.ServiceAccountReference, audience string, expiration int64) (tokenRequester, error) {
+	if ref == nil {
+		own, err := ownServiceAccount()
+		if err != nil {
+			return nil, errors.Wrap(err, errOwnServiceAccount)
+		}
+		ref = own
+	}
+
+	return func(ctx context.Context) (*authenticationv1.TokenRequest, error) {
+		sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: ref.Name, Namespace: ref.Namespace}}
+		tr := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expiration}}
+		if audience != "" {
+			tr.Spec.Audiences = []string{audience}
+		}
+		if err := kube.SubResource("token").Create(ctx, sa, tr); err != nil {
+			return nil, errors.Wrapf(err, errRequestToken, ref.Namespace, ref.Name)
+		}
+		return tr, nil
+	}, nil
+}
+
+// ownServiceAccount returns the provider's own service account, as named by
+// the subject of its mounted token.
+func ownServiceAccount() (*v1alpha1.ServiceAccountReference, error) {
+	b, err := os.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(strings.TrimSpace(string(b)), ".")
+	if len(parts) != 3 {
+		return nil, errors.New(errNotJWT)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(err, errDecodeToken)
+	}
+	claims := struct {
+		Subject string `json:"sub"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.Wrap(err, errDecodeToken)
+	}
+	sa, ok := strings.CutPrefix(claims.Subject, "system:serviceaccount:")
+	ns, name, found := strings.Cut(sa, ":")
+	if !ok || !found {
+		return nil, errors.Errorf(errNotServiceAccount, claims.Subject)
+	}
+	return &v1alpha1.ServiceAccountReference{Name: name, Namespace: ns}, nil
+}
+
+// refreshToken requests a new token, and writes it to path, each time the
+// last has been used for 80% of its lifetime, until ctx is done. Failed
+// requests are retried; the last token is valid for a while yet.
+func refreshToken(ctx context.Context, req tokenRequester, path string, after time.Duration) {
+	for {
+		t := time.NewTimer(after)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		tr, err := req(ctx)
+		if err == nil {
+			err = writeToken(path, tr.Status.Token)
+		}
+		if err != nil {
+			after = tokenRefreshRetryInterval
+			continue
+		}
+		after = refreshAfter(tr)
+	}
+}
+
+// refreshAfter returns how long the supplied token may be used before it is
+// refreshed.
+func refreshAfter(tr *authenticationv1.TokenRequest) time.Duration {
+	return max(time.Until(tr.Status.ExpirationTimestamp.Time)*4/5, tokenRefreshRetryInterval)
+}
+
+// writeToken replaces the token at path, such that Terraform's providers
+// never read a partially written one.
+func writeToken(path, token string) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(token), 0600); err != nil {
+		return errors.Wrap(err, errWriteToken)
+	}
+	return errors.Wrap(os.Rename(tmp, path), errWriteToken)
+}
+
+// exchangeAWS assumes an IAM role with web identity, as IRSA does.
+func exchangeAWS(c *credentials, w *v1alpha1.WorkloadIdentity, name, tokenPath string) error {
+	c.env["AWS_ROLE_ARN"] = w.Role
+	c.env["AWS_WEB_IDENTITY_TOKEN_FILE"] = tokenPath
+	// Session names are at most 64 characters.
+	session := "crossplane-" + name
+	c.env["AWS_ROLE_SESSION_NAME"] = session[:min(len(session), 64)]
+	return nil
+}
+
+// exchangeGCP writes an external account credentials file that exchanges
+// the token through a workload identity pool provider, and impersonates the
+// role's service account, if any.
+func exchangeGCP(c *credentials, w *v1alpha1.WorkloadIdentity, name, tokenPath string) error {
+	config := map[string]any{
+		"type":               "external_account",
+		"audience":           "//iam.googleapis.com/" + w.WorkloadIdentityProvider,
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url":          "https://sts.googleapis.com/v1/token",
+		"credential_source":  map[string]string{"file": tokenPath},
+	}
+	if w.Role != "" {
+		config["service_account_impersonation_url"] = fmt.Sprintf("https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken", w.Role)
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	path, err := c.writeFile(name, gcpExternalAccountFile, data)
+	if err != nil {
+		return err
+	}
+	c.env["GOOGLE_APPLICATION_CREDENTIALS"] = path
+	return nil
+}
+
+// exchangeAzure exchanges the token through a federated credential of the
+// role's application or managed identity.
+func exchangeAzure(c *credentials, w *v1alpha1.WorkloadIdentity, _, tokenPath string) error {
+	c.env["ARM_USE_OIDC"] = "true"
+	c.env["ARM_OIDC_TOKEN_FILE_PATH"] = tokenPath
+	c.env["ARM_CLIENT_ID"] = w.Role
+	c.env["ARM_TENANT_ID"] = w.TenantID
+	return nil
+}
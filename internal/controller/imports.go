@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/validation"
+)
+
+const (
+	errInvalidImports = "invalid imports"
+	errImportBlocked  = "import of %s would replace it; fix the configuration so it matches the existing resource"
+	importsFile       = "imports.tf"
+)
+
+// writeImportsConfig writes an import block for each import requested by the
+// supplied Terraform resource, so that Terraform adopts the existing
+// resources the next time it plans.
+func (c *TerraformExternal) writeImportsConfig(cr *v1alpha1.Terraform) error {
+	path := filepath.Join(c.service.runDir(), importsFile)
+	if len(cr.Spec.ForProvider.Imports) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Webhooks may be disabled, so the addresses are checked again before
+	// they are written to the configuration.
+	if errs := validation.Imports(cr.Spec.ForProvider.Imports, field.NewPath("spec", "forProvider", "imports")); len(errs) > 0 {
+		return withClass(v1alpha1.ReasonConfigError, errors.Wrap(errs.ToAggregate(), errInvalidImports))
+	}
+
+	var b strings.Builder
+	for _, i := range cr.Spec.ForProvider.Imports {
+		b.WriteString(fmt.Sprintf("import {\n  to = %s\n  id = %s\n}\n\n", i.Address, hclString(i.ID)))
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0600)
+}
+
+// checkImports updates the import statuses of the supplied Terraform resource
+// from the supplied plan. It returns an error if any import would replace
+// the resource it imports.
+func checkImports(cr *v1alpha1.Terraform, plan *tfjson.Plan) error {
+	importing := map[string]*tfjson.ResourceChange{}
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change != nil && rc.Change.Importing != nil {
+			importing[rc.Address] = rc
+		}
+	}
+
+	var blocked []string
+	status := make([]v1alpha1.ImportStatus, len(cr.Spec.ForProvider.Imports))
+	for n, i := range cr.Spec.ForProvider.Imports {
+		status[n] = v1alpha1.ImportStatus{Address: i.Address, ID: i.ID, Status: v1alpha1.ImportImported}
+		rc, ok := importing[i.Address]
+		if !ok {
+			// Terraform only plans imports of resources that are not yet in
+			// its state.
+			continue
+		}
+		status[n].Status = v1alpha1.ImportPending
+		if rc.Change.Actions.Replace() || rc.Change.Actions.Delete() {
+			status[n].Status = v1alpha1.ImportBlocked
+			status[n].Message = fmt.Sprintf("planned actions %v would replace the imported resource", rc.Change.Actions)
+			blocked = append(blocked, i.Address)
+		}
+	}
+	cr.Status.AtProvider.Imports = status
+
+	if len(blocked) > 0 {
+		return withClass(v1alpha1.ReasonImportBlocked, errors.Errorf(errImportBlocked, strings.Join(blocked, ", ")))
+	}
+	return nil
+}
+
+// markImported records that all imports requested by the supplied Terraform
+// resource have been applied.
+func markImported(cr *v1alpha1.Terraform) {
+	if len(cr.Spec.ForProvider.Imports) == 0 {
+		cr.Status.AtProvider.Imports = nil
+		return
+	}
+	status := make([]v1alpha1.ImportStatus, len(cr.Spec.ForProvider.Imports))
+	for n, i := range cr.Spec.ForProvider.Imports {
+		status[n] = v1alpha1.ImportStatus{Address: i.Address, ID: i.ID, Status: v1alpha1.ImportImported}
+	}
+	cr.Status.AtProvider.Imports = status
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+func TestWriteImportsConfig(t *testing.T) {
+	type want struct {
+		config string
+		err    bool
+	}
+	cases := map[string]struct {
+		reason  string
+		imports []v1alpha1.TerraformImport
+		want    want
+	}{
+		"Imports": {
+			reason: "An import block should be written for each import, with its ID taken literally.",
+			imports: []v1alpha1.TerraformImport{
+				{Address: "aws_s3_bucket.logs", ID: "logs"},
+				{Address: `module.app.aws_iam_role.this["web"]`, ID: "${role}"},
+			},
+			want: want{config: "import {\n  to = aws_s3_bucket.logs\n  id = \"logs\"\n}\n\n" +
+				"import {\n  to = module.app.aws_iam_role.this[\"web\"]\n  id = \"$${role}\"\n}\n\n"},
+		},
+		"InjectedBlock": {
+			reason: "An address that isn't only a resource address should be rejected rather than written to the configuration.",
+			imports: []v1alpha1.TerraformImport{
+				{Address: "aws_s3_bucket.logs\n  id = \"logs\"\n}\nresource \"null_resource\" \"injected\" {\n  to = null_resource.injected", ID: "logs"},
+			},
+			want: want{err: true},
+		},
+		"NoImports": {
+			reason: "No configuration should be written without imports.",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &TerraformExternal{service: &TerraformService{workDir: t.TempDir()}}
+			cr := &v1alpha1.Terraform{}
+			cr.Spec.ForProvider.Imports = tc.imports
+
+			err := e.writeImportsConfig(cr)
+			if (err != nil) != tc.want.err {
+				t.Errorf("\n%s\nwriteImportsConfig(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if tc.want.err && classifyError(err) != v1alpha1.ReasonConfigError {
+				t.Errorf("\n%s\nwriteImportsConfig(...): want %s error, got %s", tc.reason, v1alpha1.ReasonConfigError, classifyError(err))
+			}
+			got, _ := os.ReadFile(filepath.Join(e.service.runDir(), importsFile))
+			if diff := cmp.Diff(tc.want.config, string(got)); diff != "" {
+				t.Errorf("\n%s\nwriteImportsConfig(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckImports(t *testing.T) {
+	importing := func(address string, actions ...tfjson.Action) *tfjson.ResourceChange {
+		return &tfjson.ResourceChange{Address: address, Change: &tfjson.Change{Actions: actions, Importing: &tfjson.Importing{ID: "id"}}}
+	}
+	imports := []v1alpha1.TerraformImport{{Address: "aws_s3_bucket.logs", ID: "logs"}}
+
+	type want struct {
+		status []v1alpha1.ImportStatus
+		err    bool
+	}
+	cases := map[string]struct {
+		reason string
+		plan   *tfjson.Plan
+		want   want
+	}{
+		"Pending": {
+			reason: "An import the plan adopts without changes should be pending.",
+			plan:   &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{importing("aws_s3_bucket.logs", tfjson.ActionNoop)}},
+			want:   want{status: []v1alpha1.ImportStatus{{Address: "aws_s3_bucket.logs", ID: "logs", Status: v1alpha1.ImportPending}}},
+		},
+		"Updated": {
+			reason: "An import the plan updates in place should be pending.",
+			plan:   &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{importing("aws_s3_bucket.logs", tfjson.ActionUpdate)}},
+			want:   want{status: []v1alpha1.ImportStatus{{Address: "aws_s3_bucket.logs", ID: "logs", Status: v1alpha1.ImportPending}}},
+		},
+		"Replaced": {
+			reason: "An import the plan would replace should be blocked.",
+			plan:   &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{importing("aws_s3_bucket.logs", tfjson.ActionDelete, tfjson.ActionCreate)}},
+			want: want{
+				status: []v1alpha1.ImportStatus{{
+					Address: "aws_s3_bucket.logs",
+					ID:      "logs",
+					Status:  v1alpha1.ImportBlocked,
+					Message: "planned actions [delete create] would replace the imported resource",
+				}},
+				err: true,
+			},
+		},
+		"InState": {
+			reason: "An import the plan doesn't mention has already been imported.",
+			plan:   &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{{Address: "aws_s3_bucket.logs", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}}}},
+			want:   want{status: []v1alpha1.ImportStatus{{Address: "aws_s3_bucket.logs", ID: "logs", Status: v1alpha1.ImportImported}}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.Terraform{}
+			cr.Spec.ForProvider.Imports = imports
+			err := checkImports(cr, tc.plan)
+			if (err != nil) != tc.want.err {
+				t.Errorf("\n%s\ncheckImports(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if tc.want.err && classifyError(err) != v1alpha1.ReasonImportBlocked {
+				t.Errorf("\n%s\ncheckImports(...): want %s error, got %s", tc.reason, v1alpha1.ReasonImportBlocked, classifyError(err))
+			}
+			if diff := cmp.Diff(tc.want.status, cr.Status.AtProvider.Imports); diff != "" {
+				t.Errorf("\n%s\ncheckImports(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMarkImported(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		imports []v1alpha1.TerraformImport
+		want    []v1alpha1.ImportStatus
+	}{
+		"Imports": {
+			reason:  "Every import should be recorded as imported.",
+			imports: []v1alpha1.TerraformImport{{Address: "aws_s3_bucket.logs", ID: "logs"}},
+			want:    []v1alpha1.ImportStatus{{Address: "aws_s3_bucket.logs", ID: "logs", Status: v1alpha1.ImportImported}},
+		},
+		"NoImports": {
+			reason: "The statuses of removed imports should be cleared.",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.Terraform{}
+			cr.Spec.ForProvider.Imports = tc.imports
+			cr.Status.AtProvider.Imports = []v1alpha1.ImportStatus{{Address: "aws_s3_bucket.old", ID: "old", Status: v1alpha1.ImportPending}}
+			markImported(cr)
+			if diff := cmp.Diff(tc.want, cr.Status.AtProvider.Imports); diff != "" {
+				t.Errorf("\n%s\nmarkImported(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfconfig"
+	"github.com/mgeorge67701/crossplane-terraform/internal/validation"
+)
+
+const (
+	errGetWorkspace     = "cannot get referenced Workspace"
+	errInheritWorkspace = "cannot inherit the state location of referenced Workspace"
+	errWorkingDirectory = "invalid working directory %q of referenced Workspace"
+	errTerraformVersion = "cannot determine Terraform version"
+	errVersionMismatch  = "referenced Workspace requires Terraform %s, but the provider runs Terraform %s"
+)
+
+// workspaceFor returns the Workspace the supplied Terraform resource inherits
+// settings from, or nil if it references none. A deleted resource may outlive
+// its Workspace, so its resources are destroyed from the state location it
+// last recorded if the Workspace is gone.
+func workspaceFor(ctx context.Context, kube client.Reader, cr *v1alpha1.Terraform) (*v1alpha1.Workspace, error) {
+	ref := cr.Spec.ForProvider.WorkspaceRef
+	if ref == nil {
+		return nil, nil
+	}
+	ws := &v1alpha1.Workspace{}
+	err := kube.Get(ctx, types.NamespacedName{Name: ref.Name}, ws)
+	if kerrors.IsNotFound(err) && meta.WasDeleted(cr) && cr.Status.AtProvider.StateLocation != nil {
+		return recordedWorkspace(cr.Status.AtProvider.StateLocation), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errGetWorkspace)
+	}
+	// Resources may have been created in the state location before it was
+	// invalid, so are still destroyed from it.
+	if errs := validation.WorkspaceRef(cr, ws); len(errs) > 0 && !meta.WasDeleted(cr) {
+		return nil, errors.Wrap(errs.ToAggregate(), errInheritWorkspace)
+	}
+	return ws, nil
+}
+
+// recordedWorkspace returns a stand-in for a Workspace that is gone, which
+// inherits only the supplied state location.
+func recordedWorkspace(l *v1alpha1.StateLocation) *v1alpha1.Workspace {
+	ws := &v1alpha1.Workspace{}
+	ws.Spec.ForProvider.Name = l.Workspace
+	ws.Spec.ForProvider.Backend = l.Backend.DeepCopy()
+	return ws
+}
+
+// runDirFor returns the directory, relative to the working directory, that
+// Terraform runs in for a resource inheriting from the supplied Workspace.
+func runDirFor(ws *v1alpha1.Workspace) (string, error) {
+	if ws == nil || ws.Spec.ForProvider.WorkingDirectory == "" {
+		return "", nil
+	}
+	dir := ws.Spec.ForProvider.WorkingDirectory
+	return dir, errors.Wrapf(tfconfig.ValidatePath(dir), errWorkingDirectory, dir)
+}
+
+// stateLocation returns where the state of the supplied Terraform resource is
+// stored: its own backend and workspace, or those of the Workspace it
+// references.
+func (c *TerraformExternal) stateLocation(cr *v1alpha1.Terraform) v1alpha1.StateLocation {
+	fp := cr.Spec.ForProvider.DeepCopy()
+	if ws := c.workspace; ws != nil {
+		if fp.Workspace == "" {
+			fp.Workspace = ws.Spec.ForProvider.Name
+		}
+		if fp.Backend == nil {
+			fp.Backend = ws.Spec.ForProvider.Backend
+		}
+	}
+	return fp.StateLocation()
+}
+
+// inheritedVariables returns the variables of the referenced Workspace that
+// the supplied module declares. A Workspace is shared by many
+// configurations, which needn't declare every one of its variables.
+func (c *TerraformExternal) inheritedVariables(m *tfconfig.Module) map[string]string {
+	vars := map[string]string{}
+	if c.workspace == nil {
+		return vars
+	}
+	for k, v := range c.workspace.Spec.ForProvider.Variables {
+		if m.Declares(k) {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// workspaceGeneration returns the generation of the referenced Workspace, or
+// zero if there is none.
+func (c *TerraformExternal) workspaceGeneration() int64 {
+	if c.workspace == nil {
+		return 0
+	}
+	return c.workspace.GetGeneration()
+}
+
+// environment returns the environment variables Terraform is run with: those
+// of the ProviderConfig and its credentials, overridden by those of the
+// referenced Workspace.
+func (c *TerraformExternal) environment() map[string]string {
+	env := map[string]string{}
+	if c.creds != nil {
+		maps.Copy(env, c.creds.env)
+	}
+	if c.workspace != nil {
+		maps.Copy(env, c.workspace.Spec.ForProvider.Environment)
+	}
+	return env
+}
+
+// checkTerraformVersion returns an error if the referenced Workspace requires
+// a version of Terraform other than the one the provider runs.
+func (c *TerraformExternal) checkTerraformVersion(ctx context.Context, tf *tfexec.Terraform) error {
+	if c.workspace == nil || c.workspace.Spec.ForProvider.TerraformVersion == "" {
+		return nil
+	}
+	want, err := version.NewVersion(c.workspace.Spec.ForProvider.TerraformVersion)
+	if err != nil {
+		return errors.Wrap(err, errTerraformVersion)
+	}
+	got, _, err := tf.Version(ctx, true)
+	if err != nil {
+		return errors.Wrap(err, errTerraformVersion)
+	}
+	if !got.Equal(want) {
+		return errors.Errorf(errVersionMismatch, want, got)
+	}
+	return nil
+}
+
+// backend returns the backend the state of the supplied location is
+// stored in. Local state is always kept at the top of the working directory,
+// so that it isn't lost if Terraform is run in another directory.
+func (s *TerraformService) backend(l v1alpha1.StateLocation) *v1alpha1.BackendConfig {
+	if l.Backend != nil || s.dir == "" {
+		return l.Backend
+	}
+	return &v1alpha1.BackendConfig{
+		Type: "local",
+		Configuration: map[string]string{
+			"path":          filepath.Join(s.workDir, localStateFile),
+			"workspace_dir": filepath.Join(s.workDir, "terraform.tfstate.d"),
+		},
+	}
+}
+
+// localLockPath returns the file in which the local backend records the lock
+// of the state of the supplied location, or "" if it is stored in another
+// backend. The lock is kept next to the state, which is resolved as the local
+// backend resolves it, relative to the directory Terraform runs in.
+func (s *TerraformService) localLockPath(l v1alpha1.StateLocation) string {
+	b := s.backend(l)
+	if b != nil && b.Type != "local" {
+		return ""
+	}
+	path := localStateFile
+	dir := "terraform.tfstate.d"
+	if b != nil {
+		if p := b.Configuration["path"]; p != "" {
+			path = p
+		}
+		if d := b.Configuration["workspace_dir"]; d != "" {
+			dir = d
+		}
+	}
+	if l.Workspace != v1alpha1.DefaultWorkspace {
+		path = filepath.Join(dir, l.Workspace, localStateFile)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.runDir(), path)
+	}
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock.info")
+}
+
+// runDir returns the directory Terraform runs in.
+func (s *TerraformService) runDir() string {
+	return filepath.Join(s.workDir, filepath.FromSlash(s.dir))
+}
+
+// enqueueWorkspaceDependents enqueues the Terraform resources that reference
+// a Workspace.
+func enqueueWorkspaceDependents(kube client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		l := &v1alpha1.TerraformList{}
+		if err := kube.List(ctx, l); err != nil {
+			return nil
+		}
+		var reqs []reconcile.Request
+		for _, cr := range l.Items {
+			if ref := cr.Spec.ForProvider.WorkspaceRef; ref != nil && ref.Name == obj.GetName() {
+				reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Name: cr.GetName()}})
+			}
+		}
+		return reqs
+	})
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errUnlockInFlight  = "refusing to force-unlock state while this provider has a Terraform run in progress"
+	lockCreatedLayout  = "2006-01-02 15:04:05.999999999 -0700 MST"
+	defaultLockTimeout = "0s"
+)
+
+var lockInfoLine = regexp.MustCompile(`^\s*(ID|Path|Operation|Who|Created):\s*(.*?)\s*$`)
+
+// runs tracks the Terraform runs in flight in this provider process, and
+// processStarted when it started. Together with the holder and creation time
+// Terraform records in a lock, they tell whether the lock may be held by a
+// run in flight.
+var (
+	runs           = &inflightRuns{started: map[int]time.Time{}}
+	processStarted = time.Now()
+)
+
+// inflightRuns records when each in-flight Terraform run started.
+type inflightRuns struct {
+	mu      sync.Mutex
+	next    int
+	started map[int]time.Time
+}
+
+// start records the start of a run. The returned function must be called
+// when the run completes.
+func (r *inflightRuns) start() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.next
+	r.next++
+	r.started[id] = time.Now()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.started, id)
+	}
+}
+
+// startedBy returns true if a run in flight started no later than t, so may
+// have acquired a lock then.
+func (r *inflightRuns) startedBy(t time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.started {
+		if !s.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockTimeout returns the Terraform -lock-timeout option for the supplied
+// resource.
+func lockTimeout(cr *v1alpha1.Terraform) *tfexec.LockTimeoutOption {
+	if d := cr.Spec.ForProvider.LockTimeout; d != nil && d.Duration > 0 {
+		return tfexec.LockTimeout(d.Duration.String())
+	}
+	return tfexec.LockTimeout(defaultLockTimeout)
+}
+
+// parseLockInfo extracts the lock described in the output of a Terraform
+// command that failed to acquire the state lock. It returns nil if the
+// output does not describe a lock.
+func parseLockInfo(out string) *v1alpha1.StateLockInfo {
+	info := &v1alpha1.StateLockInfo{}
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		m := lockInfoLine.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		switch m[1] {
+		case "ID":
+			info.ID = m[2]
+		case "Path":
+			info.Path = m[2]
+		case "Operation":
+			info.Operation = m[2]
+		case "Who":
+			info.Who = m[2]
+		case "Created":
+			if t, err := time.Parse(lockCreatedLayout, m[2]); err == nil {
+				mt := metav1.NewTime(t)
+				info.Created = &mt
+			}
+		}
+	}
+	if info.ID == "" {
+		return nil
+	}
+	return info
+}
+
+// lockMessage describes the supplied lock, and how to release it.
+func lockMessage(l *v1alpha1.StateLockInfo) string {
+	holder := l.Who
+	if holder == "" {
+		holder = "an unknown holder"
+	}
+	age := ""
+	if l.Created != nil {
+		age = fmt.Sprintf(" for %s", time.Since(l.Created.Time).Round(time.Second))
+	}
+	return fmt.Sprintf("Terraform state is locked by %s%s (lock ID %s). If the holder is gone, set the %s annotation to the lock ID to release it.",
+		holder, age, l.ID, v1alpha1.AnnotationKeyForceUnlock)
+}
+
+// forceUnlockRequested returns true if the supplied resource requests that
+// the state lock reported in its status be forcibly released. Requests that
+// don't match the reported lock's ID are ignored.
+func forceUnlockRequested(cr *v1alpha1.Terraform) bool {
+	id := cr.GetAnnotations()[v1alpha1.AnnotationKeyForceUnlock]
+	l := cr.Status.AtProvider.StateLock
+	return id != "" && l != nil && l.ID == id
+}
+
+// forceUnlock releases the state lock reported in the status of the supplied
+// resource, if that was requested.
+func (c *TerraformExternal) forceUnlock(ctx context.Context, cr *v1alpha1.Terraform, tf *tfexec.Terraform) error {
+	if !forceUnlockRequested(cr) {
+		return nil
+	}
+	l := cr.Status.AtProvider.StateLock
+
+	// Never release a lock that may be held by one of our own runs.
+	if heldByRun(l) {
+		return errors.New(errUnlockInFlight)
+	}
+
+	if err := tf.ForceUnlock(ctx, l.ID); err != nil {
+		return errors.Wrap(err, errForceUnlockTF)
+	}
+	cr.Status.AtProvider.StateLock = nil
+	cr.Status.AtProvider.LastFailure = nil
+	cr.SetConditions(v1alpha1.TerraformSucceeded())
+	return nil
+}
+
+// heldByRun returns true if the supplied lock may be held by a Terraform run
+// in flight in this process: this process acquired it, while a run that is
+// still in flight was running. Locks acquired before this process started,
+// e.g. by a container since restarted with the same hostname, never are.
+// Lock times reported in status are truncated to the second, so a second is
+// allowed for. A lock of this process without a creation time can't be told
+// apart from one held by a run, so is assumed to be.
+func heldByRun(l *v1alpha1.StateLockInfo) bool {
+	if l.Who != lockHolder() {
+		return false
+	}
+	if l.Created == nil {
+		return true
+	}
+	created := l.Created.Add(time.Second)
+	return !created.Before(processStarted) && runs.startedBy(created)
+}
+
+// lockHolder returns the identity Terraform records for locks acquired by
+// this process.
+func lockHolder() string {
+	name := ""
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s", name, host)
+}
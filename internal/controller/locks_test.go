@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+func TestHeldByRun(t *testing.T) {
+	at := func(t time.Time) *metav1.Time {
+		mt := metav1.NewTime(t.Truncate(time.Second))
+		return &mt
+	}
+	cases := map[string]struct {
+		reason string
+		run    bool
+		lock   func(started time.Time) *v1alpha1.StateLockInfo
+		want   bool
+	}{
+		"OtherHolder": {
+			reason: "A lock acquired by another process should never be held by a run of this one.",
+			run:    true,
+			lock: func(started time.Time) *v1alpha1.StateLockInfo {
+				return &v1alpha1.StateLockInfo{ID: "a", Who: "someone@elsewhere", Created: at(started)}
+			},
+		},
+		"HeldByRun": {
+			reason: "A lock this process acquired while a run in flight was running may be held by it.",
+			run:    true,
+			lock: func(started time.Time) *v1alpha1.StateLockInfo {
+				return &v1alpha1.StateLockInfo{ID: "a", Who: lockHolder(), Created: at(started)}
+			},
+			want: true,
+		},
+		"NoRun": {
+			reason: "A lock this process acquired should not be held once no run is in flight.",
+			lock: func(started time.Time) *v1alpha1.StateLockInfo {
+				return &v1alpha1.StateLockInfo{ID: "a", Who: lockHolder(), Created: at(started)}
+			},
+		},
+		"BeforeRun": {
+			reason: "A lock acquired before every run in flight started should not be held by any of them.",
+			run:    true,
+			lock: func(started time.Time) *v1alpha1.StateLockInfo {
+				return &v1alpha1.StateLockInfo{ID: "a", Who: lockHolder(), Created: at(started.Add(-time.Minute))}
+			},
+		},
+		"PreviousProcess": {
+			reason: "A lock acquired before this process started should never be held by a run of it.",
+			run:    true,
+			lock: func(_ time.Time) *v1alpha1.StateLockInfo {
+				return &v1alpha1.StateLockInfo{ID: "a", Who: lockHolder(), Created: at(processStarted.Add(-time.Hour))}
+			},
+		},
+		"NoCreationTime": {
+			reason: "A lock of this process without a creation time should be assumed to be held.",
+			lock: func(_ time.Time) *v1alpha1.StateLockInfo {
+				return &v1alpha1.StateLockInfo{ID: "a", Who: lockHolder()}
+			},
+			want: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			started := time.Now()
+			if tc.run {
+				done := runs.start()
+				defer done()
+			}
+			if got := heldByRun(tc.lock(started)); got != tc.want {
+				t.Errorf("\n%s\nheldByRun(...): want %t, got %t", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseLockInfo(t *testing.T) {
+	created := metav1.NewTime(time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC))
+	cases := map[string]struct {
+		reason string
+		out    string
+		want   *v1alpha1.StateLockInfo
+	}{
+		"Locked": {
+			reason: "The lock described by Terraform's output should be extracted.",
+			out: `Error: Error acquiring the state lock
+
+Error message: resource temporarily unavailable
+Lock Info:
+  ID:        8f9a3c2e-0d3b-4c1e-9d0a-2b7f1e6c5a4d
+  Path:      terraform.tfstate
+  Operation: OperationTypeApply
+  Who:       root@provider-terraform-abc
+  Version:   1.7.5
+  Created:   2024-05-01 12:30:00.123456 +0000 UTC
+  Info:
+`,
+			want: &v1alpha1.StateLockInfo{
+				ID:        "8f9a3c2e-0d3b-4c1e-9d0a-2b7f1e6c5a4d",
+				Path:      "terraform.tfstate",
+				Operation: "OperationTypeApply",
+				Who:       "root@provider-terraform-abc",
+				Created:   &created,
+			},
+		},
+		"UnparseableCreated": {
+			reason: "A lock whose creation time can't be parsed should be extracted without one.",
+			out:    "Lock Info:\n  ID: abc\n  Created: yesterday\n",
+			want:   &v1alpha1.StateLockInfo{ID: "abc"},
+		},
+		"NotLocked": {
+			reason: "Output that describes no lock should return nil.",
+			out:    "Error: Invalid reference\n\nA reference to a resource type must be followed by at least one attribute access.",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := parseLockInfo(tc.out)
+			if diff := cmp.Diff(tc.want, got, cmp.Comparer(func(a, b metav1.Time) bool { return a.Equal(&b) })); diff != "" {
+				t.Errorf("\n%s\nparseLockInfo(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/pkg/errors"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errStateLocationChanged = "backend or workspace changed since the state was initialised; annotate the resource with " + v1alpha1.AnnotationKeyMigrateState + "=true to migrate the state, or revert the change"
+	errPullState            = "cannot pull state from its previous location"
+	errPushState            = "cannot push state to its new location"
+	errSelectWorkspace      = "cannot select Terraform workspace"
+
+	migratedStateFile = "migrated.tfstate"
+)
+
+// stateLocationChanged returns true if the supplied state location of the
+// supplied Terraform resource differs from where its state was initialised.
+func stateLocationChanged(cr *v1alpha1.Terraform, l v1alpha1.StateLocation) bool {
+	prev := cr.Status.AtProvider.StateLocation
+	return prev != nil && !prev.Equal(l)
+}
+
+// migrateStateRequested returns true if the state of the supplied Terraform
+// resource may be moved to a new location.
+func migrateStateRequested(cr *v1alpha1.Terraform) bool {
+	return cr.GetAnnotations()[v1alpha1.AnnotationKeyMigrateState] == "true"
+}
+
+// migrationPending returns true if the state of the supplied Terraform
+// resource is to be moved to the supplied location on its next run.
+func migrationPending(cr *v1alpha1.Terraform, l v1alpha1.StateLocation) bool {
+	return migrateStateRequested(cr) && stateLocationChanged(cr, l)
+}
+
+// checkStateLocation returns an error if the state location of the supplied
+// Terraform resource changed to the supplied location, and the state may not
+// be migrated. Planning against the new location would find no state and
+// recreate everything.
+func checkStateLocation(cr *v1alpha1.Terraform, l v1alpha1.StateLocation) error {
+	if stateLocationChanged(cr, l) && !migrateStateRequested(cr) {
+		return withClass(v1alpha1.ReasonMigrationRequired, errors.New(errStateLocationChanged))
+	}
+	return nil
+}
+
+// migrateState moves the state of the supplied Terraform resource from the
+// location it was initialised at to the supplied location. The state at the
+// previous location is left in place.
+func (c *TerraformExternal) migrateState(ctx context.Context, cr *v1alpha1.Terraform, next v1alpha1.StateLocation) error {
+	prev := *cr.Status.AtProvider.StateLocation
+
+	tf, err := c.initAt(ctx, prev)
+	if err != nil {
+		return err
+	}
+	state, err := tf.StatePull(ctx)
+	if err != nil {
+		return errors.Wrap(err, errPullState)
+	}
+
+	if tf, err = c.initAt(ctx, next); err != nil {
+		return err
+	}
+	if state == "" {
+		// There was never any state at the previous location.
+		return nil
+	}
+
+	path := filepath.Join(c.service.runDir(), migratedStateFile)
+	defer os.Remove(path) //nolint:errcheck // Only needed for the push.
+	if err := os.WriteFile(path, []byte(state), 0600); err != nil {
+		return errors.Wrap(err, errPushState)
+	}
+	// Terraform refuses to push state over a different lineage, so state
+	// already at the new location is never overwritten.
+	return errors.Wrap(tf.StatePush(ctx, path), errPushState)
+}
+
+// initAt initialises the working directory against the supplied state
+// location.
+func (c *TerraformExternal) initAt(ctx context.Context, l v1alpha1.StateLocation) (*tfexec.Terraform, error) {
+	if err := writeBackendConfig(c.service.runDir(), c.service.backend(l)); err != nil {
+		return nil, errors.Wrap(err, errWriteBackend)
+	}
+	tf, err := tfexec.NewTerraform(c.service.runDir(), "terraform")
+	if err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+	if env := c.environment(); len(env) > 0 {
+		if err := tf.SetEnv(withEnviron(env)); err != nil {
+			return nil, errors.Wrap(err, errSetEnvironment)
+		}
+	}
+	if err := tf.Init(ctx, tfexec.Reconfigure(true)); err != nil {
+		return nil, errors.Wrap(err, errInitTF)
+	}
+	return tf, errors.Wrap(selectWorkspace(ctx, tf, l.Workspace), errSelectWorkspace)
+}
+
+// selectWorkspace selects the named workspace, creating it if it does not
+// exist.
+func selectWorkspace(ctx context.Context, tf *tfexec.Terraform, name string) error {
+	workspaces, current, err := tf.WorkspaceList(ctx)
+	if err != nil {
+		return err
+	}
+	if current == name {
+		return nil
+	}
+	for _, ws := range workspaces {
+		if ws == name {
+			return tf.WorkspaceSelect(ctx, name)
+		}
+	}
+	return tf.WorkspaceNew(ctx, name)
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfconfig"
+)
+
+const (
+	errReadModule       = "cannot read the root module's interface"
+	errInvalidVariables = "variables do not match the root module: %s"
+	errUndeclared       = "variables not declared by the root module are ignored: %s"
+
+	reasonUndeclaredVariables event.Reason = "UndeclaredVariables"
+)
+
+// readModuleInterface publishes the interface of the root module of the
+// supplied Terraform resource, which is in the supplied directory relative to
+// the working directory. The configuration is only parsed, so this is much
+// cheaper than finding problems with Terraform.
+func readModuleInterface(cr *v1alpha1.Terraform, dir string) (*tfconfig.Module, error) {
+	files, err := tfconfig.Files(&cr.Spec.ForProvider, dir)
+	if err != nil {
+		return nil, withClass(v1alpha1.ReasonConfigError, errors.Wrap(err, errReadModule))
+	}
+	m, diags := tfconfig.RootModule(files, dir)
+	if diags.HasErrors() {
+		return nil, withClass(v1alpha1.ReasonConfigError, errors.Wrap(diags, errReadModule))
+	}
+	cr.Status.AtProvider.Interface = m.Interface
+	return m, nil
+}
+
+// checkVariables returns an error if the supplied variables don't satisfy
+// the interface of the supplied module.
+func checkVariables(m *tfconfig.Module, vars map[string]string) error {
+	if problems := m.CheckVariables(vars); len(problems) > 0 {
+		return withClass(v1alpha1.ReasonInvalidVariables, errors.Errorf(errInvalidVariables, strings.Join(problems, "; ")))
+	}
+	return nil
+}
+
+// recordUndeclared records the supplied variables that the supplied module
+// doesn't declare in the status of the supplied resource, and a warning event
+// if there are any. Terraform ignores them, so they don't fail the run.
+func recordUndeclared(record event.Recorder, cr *v1alpha1.Terraform, m *tfconfig.Module, vars map[string]string) {
+	names := m.Undeclared(vars)
+	cr.Status.AtProvider.UndeclaredVariables = names
+	if len(names) > 0 {
+		record.Event(cr, event.Warning(reasonUndeclaredVariables, errors.Errorf(errUndeclared, strings.Join(names, ", "))))
+	}
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errListPlanPolicies = "cannot list plan policies"
+	errConvertPlan      = "cannot convert plan for policy evaluation"
+	errPolicyViolations = "plan violates %d enforced policy rules: %s"
+	errPolicySelector   = "invalid selector"
+	errPolicyExpression = "cannot evaluate rule: %s"
+	errPolicyNotBool    = "rule evaluated to %s, not a bool"
+
+	// labelKeyClaimNamespace is the label Crossplane sets on composed
+	// resources to record the namespace of the claim they were composed for.
+	labelKeyClaimNamespace = "crossplane.io/claim-namespace"
+)
+
+// checkPlanPolicies evaluates every PlanPolicy that selects the supplied
+// Terraform resource against the supplied plan, and records any violations
+// in its status. It returns an error if any enforced rule is violated.
+func (c *TerraformExternal) checkPlanPolicies(ctx context.Context, cr *v1alpha1.Terraform, plan *tfjson.Plan) error {
+	cr.Status.AtProvider.PolicyViolations = nil
+
+	pl := &v1alpha1.PlanPolicyList{}
+	if err := c.kube.List(ctx, pl); err != nil {
+		return errors.Wrap(err, errListPlanPolicies)
+	}
+	if len(pl.Items) == 0 {
+		return nil
+	}
+
+	vars, err := policyVars(cr, plan)
+	if err != nil {
+		return errors.Wrap(err, errConvertPlan)
+	}
+
+	// Evaluate policies in a stable order, so violations are reported
+	// consistently.
+	sort.Slice(pl.Items, func(i, j int) bool { return pl.Items[i].GetName() < pl.Items[j].GetName() })
+
+	var violations []v1alpha1.PolicyViolation
+	var enforced []string
+	for _, p := range pl.Items {
+		action := p.Spec.EnforcementAction
+		if action == "" {
+			action = v1alpha1.PolicyEnforce
+		}
+		ok, err := policySelects(p, cr)
+		if err != nil {
+			// A policy we can't match against is reported against every
+			// resource, rather than silently ignored.
+			violations = append(violations, v1alpha1.PolicyViolation{Policy: p.GetName(), EnforcementAction: action, Message: errors.Wrap(err, errPolicySelector).Error()})
+			if action == v1alpha1.PolicyEnforce {
+				enforced = append(enforced, p.GetName())
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+		for _, r := range p.Spec.Rules {
+			msg, ok := evaluateRule(r, vars)
+			if ok {
+				continue
+			}
+			violations = append(violations, v1alpha1.PolicyViolation{Policy: p.GetName(), Rule: r.Name, EnforcementAction: action, Message: msg})
+			if action == v1alpha1.PolicyEnforce {
+				enforced = append(enforced, p.GetName()+"/"+r.Name)
+			}
+		}
+	}
+	cr.Status.AtProvider.PolicyViolations = violations
+
+	if len(enforced) > 0 {
+		return withClass(v1alpha1.ReasonPolicyViolation, errors.Errorf(errPolicyViolations, len(enforced), strings.Join(enforced, ", ")))
+	}
+	return nil
+}
+
+// policySelects returns true if the supplied PlanPolicy applies to the
+// supplied Terraform resource.
+func policySelects(p v1alpha1.PlanPolicy, cr *v1alpha1.Terraform) (bool, error) {
+	if len(p.Spec.Namespaces) > 0 {
+		ns := cr.GetLabels()[labelKeyClaimNamespace]
+		found := false
+		for _, n := range p.Spec.Namespaces {
+			if n == ns {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if p.Spec.Selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(p.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(cr.GetLabels())), nil
+}
+
+// policyVars returns the variables a PlanPolicy rule is evaluated with.
+func policyVars(cr *v1alpha1.Terraform, plan *tfjson.Plan) (map[string]any, error) {
+	b, err := json.Marshal(plan)
+	if err != nil {
+		return nil, err
+	}
+	p := map[string]any{}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"plan": p,
+		"object": map[string]any{
+			"name":        cr.GetName(),
+			"labels":      stringMap(cr.GetLabels()),
+			"annotations": stringMap(cr.GetAnnotations()),
+		},
+	}, nil
+}
+
+func stringMap(m map[string]string) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// evaluateRule evaluates the supplied rule. It returns true if the rule is
+// satisfied, or a message describing why it is not. Rules that cannot be
+// evaluated are never satisfied.
+func evaluateRule(r v1alpha1.PlanPolicyRule, vars map[string]any) (string, bool) {
+	msg := r.Message
+	if msg == "" {
+		msg = fmt.Sprintf("plan does not satisfy %q", r.Expression)
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("plan", cel.DynType),
+		cel.Variable("object", cel.DynType),
+		ext.Strings(),
+	)
+	if err != nil {
+		return fmt.Sprintf(errPolicyExpression, err), false
+	}
+	ast, iss := env.Compile(r.Expression)
+	if iss.Err() != nil {
+		return fmt.Sprintf(errPolicyExpression, iss.Err()), false
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return fmt.Sprintf(errPolicyExpression, err), false
+	}
+	out, _, err := prg.Eval(vars)
+	if err != nil {
+		return fmt.Sprintf(errPolicyExpression, err), false
+	}
+	ok, isBool := out.Value().(bool)
+	if !isBool {
+		return fmt.Sprintf(errPolicyNotBool, out.Type().TypeName()), false
+	}
+	return msg, ok
+}
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errCreateNotAllowed = "Terraform state is empty and the management policies do not allow creating it"
+	errReadOutputs      = "cannot read Terraform outputs"
+	errArchiveWorkDir   = "cannot archive working directory"
+
+	localStateFile = "terraform.tfstate"
+
+	// archiveDir holds archived working directories. Resource names can't
+	// contain underscores, so it is never the working directory of one.
+	archiveDir = "/tmp/terraform_archive"
+
+	// StateEmpty and StatePresent describe whether the Terraform state
+	// holds anything Terraform applied.
+	StateEmpty   = "Empty"
+	StatePresent = "Present"
+)
+
+// workDirFor returns the working directory of the named Terraform resource.
+func workDirFor(name string) string {
+	return fmt.Sprintf("/tmp/terraform-%s", name)
+}
+
+// managementPolicies returns the management policies of the supplied
+// Terraform resource. The reconciler refuses to reconcile resources whose
+// policies are not supported or not enabled, so by the time they reach an
+// external client they can be resolved as if the feature were enabled.
+func managementPolicies(cr *v1alpha1.Terraform) managed.ManagementPoliciesChecker {
+	return managed.NewManagementPoliciesResolver(true, cr.GetManagementPolicies(), cr.GetDeletionPolicy())
+}
+
+// observeOnly observes the supplied Terraform resource using a refresh-only
+// plan, which reads the current state of the managed infrastructure without
+// ever changing it.
+func (c *TerraformExternal) observeOnly(ctx context.Context, cr *v1alpha1.Terraform, tf *tfexec.Terraform) (managed.ExternalObservation, error) {
+	planPath := c.planPath()
+	defer os.Remove(planPath) //nolint:errcheck // The plan is rewritten by every run.
+
+	if _, err := tf.Plan(ctx, c.planOptions(lockTimeout(cr), tfexec.RefreshOnly(true), tfexec.Out(planPath))...); err != nil {
+		return managed.ExternalObservation{}, c.recordFailure(cr, errors.Wrap(err, errPlanTF))
+	}
+	plan, err := tf.ShowPlanFile(ctx, planPath)
+	if err != nil {
+		return managed.ExternalObservation{}, c.recordFailure(cr, errors.Wrap(err, errShowPlanTF))
+	}
+
+	exists := stateApplied(plan.PriorState)
+	cd := publishOutputs(cr, planOutputs(plan))
+	setStateStatus(cr, exists)
+	recordSuccess(cr)
+
+	// We never apply an observe-only resource, so it is always up to date.
+	return managed.ExternalObservation{
+		ResourceExists:    exists,
+		ResourceUpToDate:  true,
+		ConnectionDetails: cd,
+	}, nil
+}
+
+// stateApplied returns true if the supplied state holds anything Terraform
+// applied: resources, data sources or outputs. Terraform omits the values of
+// a state that holds nothing, e.g. one that was never applied or has been
+// destroyed.
+func stateApplied(s *tfjson.State) bool {
+	return s != nil && s.Values != nil
+}
+
+// setStateStatus records whether the Terraform state of the supplied
+// resource holds anything, and marks it available accordingly.
+func setStateStatus(cr *v1alpha1.Terraform, exists bool) {
+	if !exists {
+		cr.Status.AtProvider.State = StateEmpty
+		if !managementPolicies(cr).ShouldCreate() {
+			cr.SetConditions(xpv1.Unavailable().WithMessage(errCreateNotAllowed))
+		}
+		return
+	}
+	cr.Status.AtProvider.State = StatePresent
+	cr.SetConditions(xpv1.Available())
+}
+
+// planOutputs returns the outputs a plan expects the configuration to have.
+func planOutputs(plan *tfjson.Plan) map[string]output {
+	out := map[string]output{}
+	if plan.PlannedValues == nil {
+		return out
+	}
+	for k, o := range plan.PlannedValues.Outputs {
+		if o == nil {
+			continue
+		}
+		v, err := json.Marshal(o.Value)
+		if err != nil {
+			continue
+		}
+		out[k] = output{sensitive: o.Sensitive, value: v}
+	}
+	return out
+}
+
+// stateOutputs returns the outputs recorded in the Terraform state.
+func stateOutputs(ctx context.Context, tf *tfexec.Terraform) (map[string]output, error) {
+	meta, err := tf.Output(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadOutputs)
+	}
+	out := make(map[string]output, len(meta))
+	for k, m := range meta {
+		out[k] = output{sensitive: m.Sensitive, value: m.Value}
+	}
+	return out, nil
+}
+
+// An output of a Terraform configuration.
+type output struct {
+	sensitive bool
+	value     json.RawMessage
+}
+
+// String returns the output's value. String values are returned as is,
+// anything else as JSON.
+func (o output) String() string {
+	var s string
+	if err := json.Unmarshal(o.value, &s); err == nil {
+		return s
+	}
+	return string(o.value)
+}
+
+// publishOutputs records the non-sensitive outputs of the supplied Terraform
+// resource, and the names of the sensitive ones, in its status, and returns
+// all outputs as connection details.
+func publishOutputs(cr *v1alpha1.Terraform, outputs map[string]output) managed.ConnectionDetails {
+	cd := managed.ConnectionDetails{}
+	status := map[string]string{}
+	var sensitive []string
+	for k, o := range outputs {
+		cd[k] = []byte(o.String())
+		if o.sensitive {
+			sensitive = append(sensitive, k)
+			continue
+		}
+		status[k] = o.String()
+	}
+	slices.Sort(sensitive)
+	cr.Status.AtProvider.Outputs = status
+	cr.Status.AtProvider.SensitiveOutputs = sensitive
+	return cd
+}
+
+// A workDirFinalizer cleans up the working directory of a Terraform resource
+// when its finalizer is removed. This matters when deletion of the resource
+// does not call Delete, for example because its management policies don't
+// allow it. Working directories holding local state are archived rather than
+// removed, so the record of the orphaned infrastructure is not lost.
+type workDirFinalizer struct {
+	resource.Finalizer
+}
+
+// RemoveFinalizer cleans up the working directory, then removes the
+// finalizer.
+func (f workDirFinalizer) RemoveFinalizer(ctx context.Context, obj resource.Object) error {
+	if err := cleanupWorkDir(workDirFor(obj.GetName()), archiveDir); err != nil {
+		return err
+	}
+	return f.Finalizer.RemoveFinalizer(ctx, obj)
+}
+
+// cleanupWorkDir removes the supplied working directory, archiving it to the
+// supplied archive directory first if it holds local state.
+func cleanupWorkDir(dir, archive string) error {
+	held, err := holdsState(dir)
+	if err != nil {
+		return errors.Wrap(err, errArchiveWorkDir)
+	}
+	if !held {
+		return os.RemoveAll(dir)
+	}
+	if err := os.MkdirAll(archive, 0700); err != nil {
+		return errors.Wrap(err, errArchiveWorkDir)
+	}
+	dst := filepath.Join(archive, fmt.Sprintf("%s-%d", filepath.Base(dir), time.Now().Unix()))
+	return errors.Wrap(os.Rename(dir, dst), errArchiveWorkDir)
+}
+
+// holdsState returns true if the supplied directory holds local state
+// anywhere: that of the default workspace, of other workspaces under
+// terraform.tfstate.d, or of a working directory Terraform is run in. The
+// .terraform directories only record backend configuration, so are skipped.
+func holdsState(dir string) (bool, error) {
+	held := false
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir() && d.Name() == ".terraform":
+			return filepath.SkipDir
+		case !d.IsDir() && filepath.Ext(path) == ".tfstate":
+			held = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return held, err
+}
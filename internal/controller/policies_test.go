@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanupWorkDir(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		files   []string
+		archive bool
+	}{
+		"NoState": {
+			reason: "A working directory without state should be removed.",
+			files:  []string{"main.tf", "terraform.tfvars"},
+		},
+		"BackendOnly": {
+			reason: "The backend configuration Terraform records under .terraform is not state.",
+			files:  []string{"main.tf", ".terraform/terraform.tfstate"},
+		},
+		"DefaultWorkspace": {
+			reason:  "A working directory with state of the default workspace should be archived.",
+			files:   []string{"main.tf", "terraform.tfstate"},
+			archive: true,
+		},
+		"OtherWorkspace": {
+			reason:  "A working directory with state of another workspace should be archived.",
+			files:   []string{"main.tf", "terraform.tfstate.d/staging/terraform.tfstate"},
+			archive: true,
+		},
+		"WorkingDirectory": {
+			reason:  "A working directory with state under the directory Terraform runs in should be archived.",
+			files:   []string{"envs/prod/main.tf", "envs/prod/terraform.tfstate"},
+			archive: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			dir := filepath.Join(tmp, "terraform-network")
+			archive := filepath.Join(tmp, "archive")
+			for _, f := range tc.files {
+				path := filepath.Join(dir, filepath.FromSlash(f))
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := cleanupWorkDir(dir, archive); err != nil {
+				t.Fatalf("\n%s\ncleanupWorkDir(...): %v", tc.reason, err)
+			}
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Errorf("\n%s\ncleanupWorkDir(...): want working directory removed, got %v", tc.reason, err)
+			}
+			archived, _ := filepath.Glob(filepath.Join(archive, "terraform-network-*", filepath.FromSlash(tc.files[len(tc.files)-1])))
+			if got := len(archived) == 1; got != tc.archive {
+				t.Errorf("\n%s\ncleanupWorkDir(...): want archived %t, got %t", tc.reason, tc.archive, got)
+			}
+		})
+	}
+}
+
+func TestCleanupWorkDirMissing(t *testing.T) {
+	if err := cleanupWorkDir(filepath.Join(t.TempDir(), "missing"), t.TempDir()); err != nil {
+		t.Errorf("cleanupWorkDir(...): want no error for a missing working directory, got %v", err)
+	}
+}
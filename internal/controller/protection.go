@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errDeletionProtected  = "deletion protection is enabled; disable spec.forProvider.deletionProtection to destroy the managed infrastructure"
+	errDestructiveChanges = "plan deletes %d and replaces %d resources, more than the %d allowed; set spec.forProvider.acknowledgeDestructiveChanges to %q to apply it"
+)
+
+// checkDeletionProtection returns an error if the supplied Terraform resource
+// is protected from deletion.
+func checkDeletionProtection(cr *v1alpha1.Terraform) error {
+	if !cr.Spec.ForProvider.DeletionProtection {
+		return nil
+	}
+	return withClass(v1alpha1.ReasonDeletionProtected, errors.New(errDeletionProtected))
+}
+
+// checkDestructiveChanges returns an error if the supplied plan deletes or
+// replaces more resources than the supplied Terraform resource allows, and
+// the plan's destructive changes have not been acknowledged. It returns the
+// digest of the changes if they were acknowledged, which must be recorded
+// once they are applied.
+func checkDestructiveChanges(cr *v1alpha1.Terraform, plan *tfjson.Plan) (string, error) {
+	cr.Status.AtProvider.PendingDestructiveChanges = nil
+
+	limit := cr.Spec.ForProvider.MaxDestructiveChanges
+	if limit == nil {
+		return "", nil
+	}
+
+	dc := destructiveChanges(plan, cr.Status.AtProvider.AppliedDestructiveChanges)
+	if len(dc.Deletes)+len(dc.Replaces) <= *limit {
+		return "", nil
+	}
+	if cr.Spec.ForProvider.AcknowledgeDestructiveChanges == dc.Digest {
+		return dc.Digest, nil
+	}
+
+	cr.Status.AtProvider.PendingDestructiveChanges = dc
+	return "", withClass(v1alpha1.ReasonDestructiveChanges, errors.Errorf(errDestructiveChanges, len(dc.Deletes), len(dc.Replaces), *limit, dc.Digest))
+}
+
+// destructiveChanges returns the resources the supplied plan deletes or
+// replaces, identified by a digest of them and of the supplied digest of the
+// acknowledged changes applied before. Identical changes planned after an
+// acknowledged plan was applied, e.g. to recreate resources that drifted, so
+// need a fresh acknowledgement.
+func destructiveChanges(plan *tfjson.Plan, applied string) *v1alpha1.DestructiveChanges {
+	dc := &v1alpha1.DestructiveChanges{}
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil {
+			continue
+		}
+		switch {
+		case rc.Change.Actions.Replace():
+			dc.Replaces = append(dc.Replaces, rc.Address)
+		case rc.Change.Actions.Delete():
+			dc.Deletes = append(dc.Deletes, rc.Address)
+		}
+	}
+	sort.Strings(dc.Deletes)
+	sort.Strings(dc.Replaces)
+
+	h := sha256.New()
+	h.Write([]byte("delete:" + strings.Join(dc.Deletes, ",") + "\n"))
+	h.Write([]byte("replace:" + strings.Join(dc.Replaces, ",") + "\n"))
+	if applied != "" {
+		h.Write([]byte("after:" + applied + "\n"))
+	}
+	dc.Digest = hex.EncodeToString(h.Sum(nil))[:16]
+	return dc
+}
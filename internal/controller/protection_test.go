@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/google/go-cmp/cmp"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+func TestCheckDeletionProtection(t *testing.T) {
+	cases := map[string]struct {
+		reason    string
+		protected bool
+		want      xpv1.ConditionReason
+	}{
+		"Protected": {
+			reason:    "A protected resource should not be destroyed.",
+			protected: true,
+			want:      v1alpha1.ReasonDeletionProtected,
+		},
+		"Unprotected": {
+			reason: "An unprotected resource should be destroyed.",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.Terraform{}
+			cr.Spec.ForProvider.DeletionProtection = tc.protected
+			var got xpv1.ConditionReason
+			if err := checkDeletionProtection(cr); err != nil {
+				got = classifyError(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckDeletionProtection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDestructiveChanges(t *testing.T) {
+	plan := destructivePlan()
+	cases := map[string]struct {
+		reason  string
+		plan    *tfjson.Plan
+		applied string
+		want    *v1alpha1.DestructiveChanges
+	}{
+		"Changes": {
+			reason: "Deleted and replaced resources should be listed in order, and updates ignored.",
+			plan:   plan,
+			want: &v1alpha1.DestructiveChanges{
+				Digest:   "455638383e504a13",
+				Deletes:  []string{"aws_s3_bucket.a", "aws_s3_bucket.b"},
+				Replaces: []string{"aws_instance.web"},
+			},
+		},
+		"AfterApplied": {
+			reason:  "The same changes planned after acknowledged changes were applied should have another digest.",
+			plan:    plan,
+			applied: "455638383e504a13",
+			want: &v1alpha1.DestructiveChanges{
+				Digest:   "4864463801054b01",
+				Deletes:  []string{"aws_s3_bucket.a", "aws_s3_bucket.b"},
+				Replaces: []string{"aws_instance.web"},
+			},
+		},
+		"NoChanges": {
+			reason: "A plan without destructive changes should list none.",
+			plan:   &tfjson.Plan{},
+			want:   &v1alpha1.DestructiveChanges{Digest: "47bd2e0d1dd6d2cc"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := destructiveChanges(tc.plan, tc.applied)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ndestructiveChanges(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckDestructiveChanges(t *testing.T) {
+	digest := destructiveChanges(destructivePlan(), "").Digest
+	limit := func(n int) *int { return &n }
+
+	type want struct {
+		acknowledged string
+		reason       xpv1.ConditionReason
+		pending      bool
+	}
+	cases := map[string]struct {
+		reason      string
+		limit       *int
+		acknowledge string
+		applied     string
+		want        want
+	}{
+		"NoLimit": {
+			reason: "Plans should never be blocked without a limit.",
+		},
+		"WithinLimit": {
+			reason: "A plan within the limit should not be blocked.",
+			limit:  limit(3),
+		},
+		"Blocked": {
+			reason: "A plan over the limit should be blocked, and described in the status.",
+			limit:  limit(2),
+			want:   want{reason: v1alpha1.ReasonDestructiveChanges, pending: true},
+		},
+		"OtherAcknowledged": {
+			reason:      "A plan over the limit should be blocked if other changes were acknowledged.",
+			limit:       limit(2),
+			acknowledge: "0123456789abcdef",
+			want:        want{reason: v1alpha1.ReasonDestructiveChanges, pending: true},
+		},
+		"Acknowledged": {
+			reason:      "A plan over the limit whose changes were acknowledged should be allowed, and the acknowledgement returned.",
+			limit:       limit(2),
+			acknowledge: digest,
+			want:        want{acknowledged: digest},
+		},
+		"AcknowledgementSpent": {
+			reason:      "An acknowledgement should not allow the same changes again once its plan was applied.",
+			limit:       limit(2),
+			acknowledge: digest,
+			applied:     digest,
+			want:        want{reason: v1alpha1.ReasonDestructiveChanges, pending: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.Terraform{}
+			cr.Spec.ForProvider.MaxDestructiveChanges = tc.limit
+			cr.Spec.ForProvider.AcknowledgeDestructiveChanges = tc.acknowledge
+			cr.Status.AtProvider.AppliedDestructiveChanges = tc.applied
+			cr.Status.AtProvider.PendingDestructiveChanges = &v1alpha1.DestructiveChanges{Digest: "stale"}
+
+			acknowledged, err := checkDestructiveChanges(cr, destructivePlan())
+			got := want{acknowledged: acknowledged, pending: cr.Status.AtProvider.PendingDestructiveChanges != nil}
+			if err != nil {
+				got.reason = classifyError(err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ncheckDestructiveChanges(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// destructivePlan returns a plan that deletes two resources, replaces one and
+// updates another.
+func destructivePlan() *tfjson.Plan {
+	change := func(address string, actions ...tfjson.Action) *tfjson.ResourceChange {
+		return &tfjson.ResourceChange{Address: address, Change: &tfjson.Change{Actions: actions}}
+	}
+	return &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{
+		change("aws_s3_bucket.b", tfjson.ActionDelete),
+		change("aws_instance.web", tfjson.ActionDelete, tfjson.ActionCreate),
+		change("aws_s3_bucket.a", tfjson.ActionDelete),
+		change("aws_iam_role.app", tfjson.ActionUpdate),
+		{Address: "data.aws_caller_identity.current"},
+	}}
+}
@@ -2,36 +2,181 @@ package controller
 
 import (
 	"context"
+	"os"
+	"time"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/providerconfig"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/validation"
 )
 
-// SetupProviderConfig adds a controller that reconciles ProviderConfigs.
+const (
+	errGetProviderConfig    = "cannot get ProviderConfig"
+	errUpdatePCStatus       = "cannot update ProviderConfig status"
+	errInvalidPC            = "invalid ProviderConfig"
+	errProbeDir             = "cannot create probe directory"
+	errProbeVersion         = "cannot determine Terraform version"
+	errProbeVersionMismatch = "ProviderConfig requires Terraform %s, but the provider runs Terraform %s"
+	errProbeBackend         = "cannot initialise backend"
+
+	// healthyInterval and unhealthyInterval are how often healthy and
+	// unhealthy ProviderConfigs are checked again, e.g. for a Secret that
+	// changed or was created.
+	healthyInterval   = 10 * time.Minute
+	unhealthyInterval = 1 * time.Minute
+
+	probeTimeout = 2 * time.Minute
+)
+
+// SetupProviderConfig adds the controllers that reconcile ProviderConfigs. One
+// counts the resources that use each ProviderConfig, and blocks its deletion
+// while any do; they couldn't be destroyed without its credentials. The other
+// checks that it is usable.
 func SetupProviderConfig(mgr ctrl.Manager, o controller.Options) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		Named("providerconfig").
+	name := providerconfig.ControllerName(v1alpha1.ProviderConfigGroupKind.Kind)
+
+	of := resource.ProviderConfigKinds{
+		Config:    v1alpha1.ProviderConfigGroupVersionKind,
+		Usage:     v1alpha1.ProviderConfigUsageGroupVersionKind,
+		UsageList: v1alpha1.ProviderConfigUsageListGroupVersionKind,
+	}
+	r := providerconfig.NewReconciler(mgr, of,
+		providerconfig.WithLogger(o.Logger.WithValues("controller", name)),
+		providerconfig.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))
+
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named(name).
 		WithOptions(o.ForControllerRuntime()).
 		For(&v1alpha1.ProviderConfig{}).
+		Watches(&v1alpha1.ProviderConfigUsage{}, &resource.EnqueueRequestForProviderConfig{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter)); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name+"/health").
+		WithOptions(o.ForControllerRuntime()).
+		For(&v1alpha1.ProviderConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(&ProviderConfigReconciler{
 			Client: mgr.GetClient(),
 			Scheme: mgr.GetScheme(),
 		})
 }
 
-// ProviderConfigReconciler reconciles a ProviderConfig object
+// ProviderConfigReconciler checks the health of ProviderConfig objects
 type ProviderConfigReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 }
 
-// Reconcile is part of the main kubernetes reconciliation loop
+// Reconcile checks that a ProviderConfig is valid, that its credentials exist
+// and, if it asks to be probed, that Terraform and its backend work with it.
+// The result is reported by its Ready condition. Synced reports whether it
+// could be checked at all. Deleted ProviderConfigs aren't checked.
 func (r *ProviderConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	// For now, we just return success. In a real implementation, this would
-	// handle provider configuration validation, credential management, etc.
-	return ctrl.Result{}, nil
+	pc := &v1alpha1.ProviderConfig{}
+	if err := r.Get(ctx, req.NamespacedName, pc); err != nil {
+		return ctrl.Result{}, errors.Wrap(resource.IgnoreNotFound(err), errGetProviderConfig)
+	}
+	if meta.WasDeleted(pc) {
+		return ctrl.Result{}, nil
+	}
+
+	after := healthyInterval
+	ready := xpv1.Available()
+	if err := r.check(ctx, pc); err != nil {
+		after = unhealthyInterval
+		ready = xpv1.Unavailable().WithMessage(err.Error())
+	}
+	pc.Status.SetConditions(ready, xpv1.ReconcileSuccess())
+
+	return ctrl.Result{RequeueAfter: after}, errors.Wrap(r.Status().Update(ctx, pc), errUpdatePCStatus)
+}
+
+// check returns an error if the supplied ProviderConfig is unusable.
+func (r *ProviderConfigReconciler) check(ctx context.Context, pc *v1alpha1.ProviderConfig) error {
+	if errs := validation.ProviderConfig(pc); len(errs) > 0 {
+		return errors.Wrap(errs.ToAggregate(), errInvalidPC)
+	}
+	if pc.Spec.Probe == nil {
+		pc.Status.TerraformVersion = ""
+		pc.Status.LastProbeTime = nil
+		// Reading dynamic credentials from Vault issues them, so only
+		// logging in is checked unless Terraform is to be run with them.
+		if pc.Spec.Credentials.Source == v1alpha1.CredentialsSourceVault {
+			return checkVault(ctx, r.Client, pc.Spec.Credentials.Vault)
+		}
+	}
+	creds, err := newCredentials(ctx, r.Client, pc)
+	defer creds.close()
+	if err != nil || pc.Spec.Probe == nil {
+		return err
+	}
+	if err := creds.load(ctx); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	now := metav1.Now()
+	pc.Status.LastProbeTime = &now
+	return probe(ctx, pc, creds)
+}
+
+// probe runs Terraform with the supplied ProviderConfig and its credentials,
+// checking that it is the required version and, if asked to, that its backend
+// can be initialised. Initialising a backend doesn't install any providers,
+// so is cheap.
+func probe(ctx context.Context, pc *v1alpha1.ProviderConfig, creds *credentials) error {
+	dir, err := os.MkdirTemp("", "providerconfig-probe-")
+	if err != nil {
+		return errors.Wrap(err, errProbeDir)
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck // Nothing can be done about it.
+
+	tf, err := tfexec.NewTerraform(dir, "terraform")
+	if err != nil {
+		return errors.Wrap(err, errNewClient)
+	}
+	if len(creds.env) > 0 {
+		if err := tf.SetEnv(withEnviron(creds.env)); err != nil {
+			return errors.Wrap(err, errSetEnvironment)
+		}
+	}
+
+	got, _, err := tf.Version(ctx, true)
+	if err != nil {
+		return errors.Wrap(err, errProbeVersion)
+	}
+	pc.Status.TerraformVersion = got.String()
+	if v := pc.Spec.TerraformVersion; v != "" {
+		// The version was validated with the rest of the spec.
+		if want, _ := version.NewVersion(v); !got.Equal(want) {
+			return errors.Errorf(errProbeVersionMismatch, want, got)
+		}
+	}
+
+	if !pc.Spec.Probe.Backend || pc.Spec.Backend == nil {
+		return nil
+	}
+	if err := writeBackendConfig(dir, pc.Spec.Backend); err != nil {
+		return errors.Wrap(err, errWriteBackend)
+	}
+	return errors.Wrap(tf.Init(ctx, tfexec.Reconfigure(true)), errProbeBackend)
 }
@@ -0,0 +1,327 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfe"
+)
+
+const (
+	errGetRemoteToken     = "cannot get remote workspace API token"
+	errReadRemote         = "cannot read remote workspace"
+	errCreateRemote       = "cannot create remote workspace"
+	errUpdateRemote       = "cannot update remote workspace"
+	errDeleteRemote       = "cannot delete remote workspace"
+	errSyncVariables      = "cannot sync remote workspace variables"
+	errQueueRun           = "cannot queue run of remote workspace"
+	errReadRun            = "cannot read current run of remote workspace"
+	errDestroyRunFinished = "destroy run %s finished with status %q without destroying every resource; queued destroy run %s"
+	errDestroyRunErrored  = "destroy run %s errored; fix the cause, then change the Workspace or annotate it with %s=%s to destroy again"
+
+	// managedVariable describes the variables of a remote workspace that
+	// are managed by its Workspace resource. Other variables are left alone.
+	managedVariable = "Managed by Crossplane"
+
+	runMessageVariables = "Queued by Crossplane: variables changed"
+	runMessageDestroy   = "Queued by Crossplane: Workspace deleted"
+
+	// WorkspaceLocked is the status of a remote workspace that is locked.
+	WorkspaceLocked = "Locked"
+)
+
+// observeRemote observes the remote workspace of the supplied Workspace
+// resource.
+func (c *WorkspaceExternal) observeRemote(ctx context.Context, cr *v1alpha1.Workspace) (managed.ExternalObservation, error) {
+	r := cr.Spec.ForProvider.Remote
+	ws, err := c.remote.ReadWorkspace(ctx, r.Organization, cr.Spec.ForProvider.Name)
+	if tfe.IsNotFound(err) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errReadRemote)
+	}
+	if err := c.observeRemoteWorkspace(ctx, cr, ws); err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	vars, err := c.remote.ListVariables(ctx, ws.ID)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errSyncVariables)
+	}
+	create, update, remove := diffVariables(desiredVariables(cr), vars)
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: remoteSettingsUpToDate(cr, ws) && len(create)+len(update)+len(remove) == 0,
+	}, nil
+}
+
+// createRemote creates the remote workspace of the supplied Workspace
+// resource.
+func (c *WorkspaceExternal) createRemote(ctx context.Context, cr *v1alpha1.Workspace) (managed.ExternalCreation, error) {
+	ws, err := c.remote.CreateWorkspace(ctx, cr.Spec.ForProvider.Remote.Organization, remoteSettings(cr))
+	if err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errCreateRemote)
+	}
+	if _, err := c.syncVariables(ctx, cr, ws.ID); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	return managed.ExternalCreation{}, c.observeRemoteWorkspace(ctx, cr, ws)
+}
+
+// updateRemote updates the settings and variables of the remote workspace of
+// the supplied Workspace resource. A run is queued if its variables changed
+// and it has been run before; a workspace that has never run may not have
+// any configuration to run.
+func (c *WorkspaceExternal) updateRemote(ctx context.Context, cr *v1alpha1.Workspace) (managed.ExternalUpdate, error) {
+	ws, err := c.remote.ReadWorkspace(ctx, cr.Spec.ForProvider.Remote.Organization, cr.Spec.ForProvider.Name)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errReadRemote)
+	}
+	if !remoteSettingsUpToDate(cr, ws) {
+		if ws, err = c.remote.UpdateWorkspace(ctx, ws.ID, remoteSettings(cr)); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errUpdateRemote)
+		}
+	}
+
+	changed, err := c.syncVariables(ctx, cr, ws.ID)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	if changed && ws.CurrentRunID != "" {
+		run, err := c.remote.CreateRun(ctx, ws.ID, tfe.RunOptions{Message: runMessageVariables})
+		if err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errQueueRun)
+		}
+		setLastRun(cr, run)
+	}
+	return managed.ExternalUpdate{}, nil
+}
+
+// deleteRemote deletes the remote workspace of the supplied Workspace
+// resource. A workspace whose state still manages resources is only deleted
+// if the Workspace allows it, once a destroy run has destroyed them. Delete
+// is called until the workspace no longer exists, so each call only moves
+// the deletion on one step. The workspace is read afresh first, as the
+// observed status may be out of date.
+func (c *WorkspaceExternal) deleteRemote(ctx context.Context, cr *v1alpha1.Workspace) (managed.ExternalDelete, error) {
+	ws, err := c.remote.ReadWorkspace(ctx, cr.Spec.ForProvider.Remote.Organization, cr.Spec.ForProvider.Name)
+	if tfe.IsNotFound(err) {
+		return managed.ExternalDelete{}, nil
+	}
+	if err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errReadRemote)
+	}
+	if err := c.observeRemoteWorkspace(ctx, cr, ws); err != nil {
+		return managed.ExternalDelete{}, err
+	}
+
+	o := cr.Status.AtProvider
+	if o.ResourceCount == 0 {
+		return managed.ExternalDelete{}, errors.Wrap(c.remote.SafeDeleteWorkspace(ctx, o.ID), errDeleteRemote)
+	}
+	if !cr.Spec.ForProvider.AutoDestroy {
+		return managed.ExternalDelete{}, errors.Errorf(errWorkspaceHasResource, cr.Spec.ForProvider.Name, o.ResourceCount)
+	}
+
+	last := o.LastRun
+	if last != nil && last.IsDestroy && !tfe.IsFinal(last.Status) {
+		// Wait for the destroy run to finish.
+		return managed.ExternalDelete{}, nil
+	}
+	if destroyErrored(cr, last) {
+		// Another destroy would most likely fail the same way, and destroy
+		// runs are applied without confirmation.
+		return managed.ExternalDelete{}, errors.Errorf(errDestroyRunErrored, last.ID, v1alpha1.AnnotationKeyRetryDestroy, last.ID)
+	}
+
+	// Destroying resources was explicitly allowed, so the run needn't be
+	// confirmed.
+	autoApply := true
+	run, err := c.remote.CreateRun(ctx, o.ID, tfe.RunOptions{Message: runMessageDestroy, IsDestroy: true, AutoApply: &autoApply})
+	if err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errQueueRun)
+	}
+	setLastRun(cr, run)
+	cr.Status.AtProvider.LastRun.Generation = cr.GetGeneration()
+
+	if last != nil && last.IsDestroy {
+		// Report a destroy run that didn't destroy everything, and back off
+		// before checking on the next one.
+		return managed.ExternalDelete{}, errors.Errorf(errDestroyRunFinished, last.ID, last.Status, run.ID)
+	}
+	return managed.ExternalDelete{}, nil
+}
+
+// destroyErrored returns true if the supplied run is a destroy run Crossplane
+// queued for the current spec of the supplied Workspace resource that
+// errored, and hasn't been asked to be retried.
+func destroyErrored(cr *v1alpha1.Workspace, run *v1alpha1.WorkspaceRun) bool {
+	if run == nil || !run.IsDestroy || run.Status != tfe.RunErrored {
+		return false
+	}
+	return run.Generation == cr.GetGeneration() && cr.GetAnnotations()[v1alpha1.AnnotationKeyRetryDestroy] != run.ID
+}
+
+// observeRemoteWorkspace records the supplied remote workspace, and its
+// current run, in the status of the supplied Workspace resource.
+func (c *WorkspaceExternal) observeRemoteWorkspace(ctx context.Context, cr *v1alpha1.Workspace, ws *tfe.Workspace) error {
+	o := &cr.Status.AtProvider
+	o.ID = ws.ID
+	o.Status = WorkspaceAvailable
+	if ws.Locked {
+		o.Status = WorkspaceLocked
+	}
+	o.ResourceCount = ws.ResourceCount
+	o.CreatedAt = metaTime(ws.CreatedAt)
+	o.UpdatedAt = metaTime(ws.UpdatedAt)
+
+	if ws.CurrentRunID == "" {
+		o.CurrentRunID = ""
+		o.LastRun = nil
+		return nil
+	}
+	run, err := c.remote.ReadRun(ctx, ws.CurrentRunID)
+	if err != nil {
+		return errors.Wrap(err, errReadRun)
+	}
+	setLastRun(cr, run)
+	return nil
+}
+
+// setLastRun records the supplied run as the current run of the supplied
+// Workspace resource.
+func setLastRun(cr *v1alpha1.Workspace, run *tfe.Run) {
+	var generation int64
+	if last := cr.Status.AtProvider.LastRun; last != nil && last.ID == run.ID {
+		generation = last.Generation
+	}
+	cr.Status.AtProvider.CurrentRunID = run.ID
+	cr.Status.AtProvider.LastRun = &v1alpha1.WorkspaceRun{
+		ID:         run.ID,
+		Status:     run.Status,
+		Message:    run.Message,
+		IsDestroy:  run.IsDestroy,
+		CreatedAt:  metaTime(run.CreatedAt),
+		Generation: generation,
+	}
+}
+
+func metaTime(t time.Time) *metav1.Time {
+	if t.IsZero() {
+		return nil
+	}
+	mt := metav1.NewTime(t)
+	return &mt
+}
+
+// remoteSettings returns the settings of the remote workspace of the supplied
+// Workspace resource.
+func remoteSettings(cr *v1alpha1.Workspace) tfe.WorkspaceOptions {
+	fp := cr.Spec.ForProvider
+	return tfe.WorkspaceOptions{
+		Name:             fp.Name,
+		AutoApply:        fp.AutoApply,
+		TerraformVersion: fp.TerraformVersion,
+		WorkingDirectory: fp.WorkingDirectory,
+	}
+}
+
+// remoteSettingsUpToDate returns true if the supplied remote workspace has
+// the settings of the supplied Workspace resource. A remote workspace keeps
+// its Terraform version unless one is set.
+func remoteSettingsUpToDate(cr *v1alpha1.Workspace, ws *tfe.Workspace) bool {
+	want := remoteSettings(cr)
+	return ws.AutoApply == want.AutoApply &&
+		ws.WorkingDirectory == want.WorkingDirectory &&
+		(want.TerraformVersion == "" || ws.TerraformVersion == want.TerraformVersion)
+}
+
+// desiredVariables returns the variables the remote workspace of the
+// supplied Workspace resource should have.
+func desiredVariables(cr *v1alpha1.Workspace) []tfe.Variable {
+	vars := make([]tfe.Variable, 0, len(cr.Spec.ForProvider.Variables)+len(cr.Spec.ForProvider.Environment))
+	for k, v := range cr.Spec.ForProvider.Variables {
+		vars = append(vars, tfe.Variable{Key: k, Value: v, Category: tfe.CategoryTerraform, Description: managedVariable})
+	}
+	for k, v := range cr.Spec.ForProvider.Environment {
+		vars = append(vars, tfe.Variable{Key: k, Value: v, Category: tfe.CategoryEnv, Description: managedVariable})
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		if vars[i].Category != vars[j].Category {
+			return vars[i].Category < vars[j].Category
+		}
+		return vars[i].Key < vars[j].Key
+	})
+	return vars
+}
+
+// diffVariables returns the variables that must be created, updated and
+// removed for a remote workspace with the supplied existing variables to have
+// the supplied desired variables. Existing variables with the same key and
+// category as a desired variable are taken over; other existing variables
+// are only removed if they were managed by the Workspace resource.
+// Existing sensitive variables are never touched.
+func diffVariables(desired, existing []tfe.Variable) (create, update, remove []tfe.Variable) {
+	type key struct{ category, key string }
+	have := make(map[key]tfe.Variable, len(existing))
+	for _, v := range existing {
+		have[key{v.Category, v.Key}] = v
+	}
+
+	want := make(map[key]bool, len(desired))
+	for _, v := range desired {
+		k := key{v.Category, v.Key}
+		want[k] = true
+		e, ok := have[k]
+		switch {
+		case !ok:
+			create = append(create, v)
+		case e.Sensitive:
+			// The values of sensitive variables can't be read, or made
+			// readable again, so they're left alone.
+		case e.Value != v.Value || e.Description != v.Description:
+			v.ID = e.ID
+			update = append(update, v)
+		}
+	}
+	for _, v := range existing {
+		if !want[key{v.Category, v.Key}] && v.Description == managedVariable {
+			remove = append(remove, v)
+		}
+	}
+	return create, update, remove
+}
+
+// syncVariables makes the variables of the identified remote workspace those
+// of the supplied Workspace resource. It returns true if any changed.
+func (c *WorkspaceExternal) syncVariables(ctx context.Context, cr *v1alpha1.Workspace, id string) (bool, error) {
+	existing, err := c.remote.ListVariables(ctx, id)
+	if err != nil {
+		return false, errors.Wrap(err, errSyncVariables)
+	}
+	create, update, remove := diffVariables(desiredVariables(cr), existing)
+	for _, v := range create {
+		if err := c.remote.CreateVariable(ctx, id, v); err != nil {
+			return false, errors.Wrap(err, errSyncVariables)
+		}
+	}
+	for _, v := range update {
+		if err := c.remote.UpdateVariable(ctx, id, v); err != nil {
+			return false, errors.Wrap(err, errSyncVariables)
+		}
+	}
+	for _, v := range remove {
+		if err := c.remote.DeleteVariable(ctx, id, v.ID); err != nil {
+			return false, errors.Wrap(err, errSyncVariables)
+		}
+	}
+	return len(create)+len(update)+len(remove) > 0, nil
+}
@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfe"
+)
+
+func TestDiffVariables(t *testing.T) {
+	region := tfe.Variable{Key: "region", Value: "us-east-1", Category: tfe.CategoryTerraform, Description: managedVariable}
+	tfLog := tfe.Variable{Key: "TF_LOG", Value: "DEBUG", Category: tfe.CategoryEnv, Description: managedVariable}
+
+	type want struct {
+		create, update, remove []tfe.Variable
+	}
+	cases := map[string]struct {
+		reason   string
+		desired  []tfe.Variable
+		existing []tfe.Variable
+		want     want
+	}{
+		"Create": {
+			reason:  "Desired variables that don't exist should be created.",
+			desired: []tfe.Variable{region, tfLog},
+			want:    want{create: []tfe.Variable{region, tfLog}},
+		},
+		"UpToDate": {
+			reason:   "Nothing should change if every variable is as desired.",
+			desired:  []tfe.Variable{region},
+			existing: []tfe.Variable{withID(region, "var-1")},
+		},
+		"Update": {
+			reason:   "Existing variables with another value should be updated by ID.",
+			desired:  []tfe.Variable{region},
+			existing: []tfe.Variable{withID(withValue(region, "eu-west-1"), "var-1")},
+			want:     want{update: []tfe.Variable{withID(region, "var-1")}},
+		},
+		"TakeOver": {
+			reason:   "Existing variables not yet managed should be taken over.",
+			desired:  []tfe.Variable{region},
+			existing: []tfe.Variable{{ID: "var-1", Key: "region", Value: "us-east-1", Category: tfe.CategoryTerraform}},
+			want:     want{update: []tfe.Variable{withID(region, "var-1")}},
+		},
+		"Category": {
+			reason:   "A variable of another category should be a different variable.",
+			desired:  []tfe.Variable{region},
+			existing: []tfe.Variable{{ID: "var-1", Key: "region", Value: "us-east-1", Category: tfe.CategoryEnv}},
+			want:     want{create: []tfe.Variable{region}},
+		},
+		"Sensitive": {
+			reason:   "Sensitive variables should never be touched, as their values can't be read.",
+			desired:  []tfe.Variable{region},
+			existing: []tfe.Variable{{ID: "var-1", Key: "region", Category: tfe.CategoryTerraform, Sensitive: true}},
+		},
+		"Remove": {
+			reason:   "Managed variables that are no longer desired should be removed.",
+			existing: []tfe.Variable{withID(tfLog, "var-2")},
+			want:     want{remove: []tfe.Variable{withID(tfLog, "var-2")}},
+		},
+		"Unmanaged": {
+			reason:   "Variables that aren't managed should be left alone.",
+			existing: []tfe.Variable{{ID: "var-3", Key: "owner", Value: "platform", Category: tfe.CategoryTerraform}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			create, update, remove := diffVariables(tc.desired, tc.existing)
+			if diff := cmp.Diff(tc.want, want{create, update, remove}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ndiffVariables(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func withID(v tfe.Variable, id string) tfe.Variable {
+	v.ID = id
+	return v
+}
+
+func withValue(v tfe.Variable, value string) tfe.Variable {
+	v.Value = value
+	return v
+}
+
+// A fakeRemote serves a remote workspace with the supplied resources and
+// current run, and records the destroy runs queued, and the workspaces
+// deleted, through the API.
+type fakeRemote struct {
+	resources int
+	current   *tfe.Run
+	gone      bool
+
+	runs    []map[string]any
+	deleted []string
+}
+
+func (f *fakeRemote) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v2/organizations/acme/workspaces/network":
+		if f.gone {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		ws := map[string]any{"id": "ws-1", "type": "workspaces", "attributes": map[string]any{"name": "network", "resource-count": f.resources}}
+		if f.current != nil {
+			ws["relationships"] = map[string]any{"current-run": map[string]any{"data": map[string]any{"id": f.current.ID, "type": "runs"}}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": ws})
+	case r.Method == http.MethodGet && f.current != nil && r.URL.Path == "/api/v2/runs/"+f.current.ID:
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"id":         f.current.ID,
+			"type":       "runs",
+			"attributes": map[string]any{"status": f.current.Status, "is-destroy": f.current.IsDestroy},
+		}})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/runs":
+		in := struct {
+			Data struct {
+				Attributes map[string]any `json:"attributes"`
+			} `json:"data"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		f.runs = append(f.runs, in.Data.Attributes)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data":{"id":"run-new","type":"runs","attributes":{"status":"pending","is-destroy":true,"message":"` + runMessageDestroy + `"}}}`))
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/actions/safe-delete"):
+		f.deleted = append(f.deleted, strings.Split(r.URL.Path, "/")[4])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestDeleteRemote(t *testing.T) {
+	erroredDestroy := &tfe.Run{ID: "run-2", Status: tfe.RunErrored, IsDestroy: true}
+	queuedErrored := &v1alpha1.WorkspaceRun{ID: "run-2", Status: tfe.RunErrored, IsDestroy: true, Generation: 1}
+
+	type want struct {
+		err        bool
+		runs       int
+		deleted    []string
+		lastRun    string
+		generation int64
+	}
+	cases := map[string]struct {
+		reason      string
+		autoDestroy bool
+		generation  int64
+		annotations map[string]string
+		remote      *fakeRemote
+		o           v1alpha1.WorkspaceObservation
+		want        want
+	}{
+		"NoResources": {
+			reason: "A workspace without resources should be deleted.",
+			remote: &fakeRemote{},
+			want:   want{deleted: []string{"ws-1"}},
+		},
+		"Gone": {
+			reason: "Nothing should happen if the workspace no longer exists.",
+			remote: &fakeRemote{gone: true},
+			o:      v1alpha1.WorkspaceObservation{ID: "ws-1", ResourceCount: 2},
+		},
+		"StaleStatus": {
+			reason: "Resources created since the workspace was last observed should prevent its deletion.",
+			remote: &fakeRemote{resources: 2},
+			o:      v1alpha1.WorkspaceObservation{ID: "ws-1"},
+			want:   want{err: true},
+		},
+		"ResourcesNotAllowed": {
+			reason: "A workspace with resources should not be deleted unless the Workspace allows it.",
+			remote: &fakeRemote{resources: 2},
+			want:   want{err: true},
+		},
+		"QueueDestroy": {
+			reason:      "A destroy run should be queued, and applied without confirmation, if the Workspace allows it.",
+			autoDestroy: true,
+			generation:  1,
+			remote:      &fakeRemote{resources: 2, current: &tfe.Run{ID: "run-1", Status: "applied"}},
+			want:        want{runs: 1, lastRun: "run-new", generation: 1},
+		},
+		"WaitForDestroy": {
+			reason:      "Nothing should happen while a destroy run is in progress.",
+			autoDestroy: true,
+			remote:      &fakeRemote{resources: 2, current: &tfe.Run{ID: "run-2", Status: "applying", IsDestroy: true}},
+			want:        want{lastRun: "run-2"},
+		},
+		"DestroyFinished": {
+			reason:      "A destroy run that applied without destroying everything should be reported, and another queued.",
+			autoDestroy: true,
+			generation:  1,
+			remote:      &fakeRemote{resources: 1, current: &tfe.Run{ID: "run-2", Status: "applied", IsDestroy: true}},
+			want:        want{err: true, runs: 1, lastRun: "run-new", generation: 1},
+		},
+		"DestroyErrored": {
+			reason:      "A destroy run Crossplane queued that errored should be reported, and not queued again.",
+			autoDestroy: true,
+			generation:  1,
+			remote:      &fakeRemote{resources: 1, current: erroredDestroy},
+			o:           v1alpha1.WorkspaceObservation{ID: "ws-1", ResourceCount: 1, LastRun: queuedErrored},
+			want:        want{err: true, lastRun: "run-2", generation: 1},
+		},
+		"DestroyErroredSpecChanged": {
+			reason:      "A destroy run that errored should be queued again once the Workspace's spec changed.",
+			autoDestroy: true,
+			generation:  2,
+			remote:      &fakeRemote{resources: 1, current: erroredDestroy},
+			o:           v1alpha1.WorkspaceObservation{ID: "ws-1", ResourceCount: 1, LastRun: queuedErrored},
+			want:        want{err: true, runs: 1, lastRun: "run-new", generation: 2},
+		},
+		"DestroyErroredRetry": {
+			reason:      "A destroy run that errored should be queued again if the Workspace asks for it to be retried.",
+			autoDestroy: true,
+			generation:  1,
+			annotations: map[string]string{v1alpha1.AnnotationKeyRetryDestroy: "run-2"},
+			remote:      &fakeRemote{resources: 1, current: erroredDestroy},
+			o:           v1alpha1.WorkspaceObservation{ID: "ws-1", ResourceCount: 1, LastRun: queuedErrored},
+			want:        want{err: true, runs: 1, lastRun: "run-new", generation: 1},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.remote)
+			defer srv.Close()
+			api, err := tfe.NewClient(srv.URL, "token")
+			if err != nil {
+				t.Fatalf("tfe.NewClient(...): %v", err)
+			}
+
+			cr := &v1alpha1.Workspace{}
+			cr.SetGeneration(tc.generation)
+			cr.SetAnnotations(tc.annotations)
+			cr.Spec.ForProvider.Name = "network"
+			cr.Spec.ForProvider.Remote = &v1alpha1.RemoteWorkspace{Organization: "acme"}
+			cr.Spec.ForProvider.AutoDestroy = tc.autoDestroy
+			cr.Status.AtProvider = tc.o
+
+			e := &WorkspaceExternal{remote: api}
+			_, err = e.deleteRemote(context.Background(), cr)
+			if (err != nil) != tc.want.err {
+				t.Errorf("\n%s\ndeleteRemote(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if diff := cmp.Diff(tc.want.deleted, tc.remote.deleted); diff != "" {
+				t.Errorf("\n%s\ndeleteRemote(...): -want deleted, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.runs, len(tc.remote.runs)); diff != "" {
+				t.Errorf("\n%s\ndeleteRemote(...): -want runs queued, +got:\n%s", tc.reason, diff)
+			}
+			for _, run := range tc.remote.runs {
+				if run["is-destroy"] != true || run["auto-apply"] != true {
+					t.Errorf("\n%s\ndeleteRemote(...): want auto-applied destroy run, got %v", tc.reason, run)
+				}
+			}
+			var got want
+			if last := cr.Status.AtProvider.LastRun; last != nil {
+				got.lastRun, got.generation = last.ID, last.Generation
+			}
+			if diff := cmp.Diff(tc.want.lastRun, got.lastRun); diff != "" {
+				t.Errorf("\n%s\ndeleteRemote(...): -want last run, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.generation, got.generation); diff != "" {
+				t.Errorf("\n%s\ndeleteRemote(...): -want generation of last run, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"regexp"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errWaitSpecChange = "configuration error; waiting for the resource's spec to change before retrying"
+	errWaitRetry      = "waiting until %s before retrying %s failure"
+)
+
+// A retryPolicy determines when a failed Terraform operation is retried.
+type retryPolicy struct {
+	// base is the delay before the first retry. It doubles with each
+	// consecutive failure until it reaches max.
+	base time.Duration
+	max  time.Duration
+
+	// waitForSpecChange prevents any retry until the resource's spec, and
+	// thus its generation, changes.
+	waitForSpecChange bool
+}
+
+var retryPolicies = map[xpv1.ConditionReason]retryPolicy{
+	v1alpha1.ReasonStateLocked:        {base: 1 * time.Minute, max: 1 * time.Minute},
+	v1alpha1.ReasonThrottled:          {base: 30 * time.Second, max: 30 * time.Minute},
+	v1alpha1.ReasonAuthFailure:        {base: 10 * time.Minute, max: 10 * time.Minute},
+	v1alpha1.ReasonConfigError:        {waitForSpecChange: true},
+	v1alpha1.ReasonNetworkError:       {base: 10 * time.Second, max: 5 * time.Minute},
+	v1alpha1.ReasonImportBlocked:      {waitForSpecChange: true},
+	v1alpha1.ReasonDeletionProtected:  {waitForSpecChange: true},
+	v1alpha1.ReasonDestructiveChanges: {waitForSpecChange: true},
+	v1alpha1.ReasonPolicyViolation:    {base: 5 * time.Minute, max: 5 * time.Minute},
+	v1alpha1.ReasonMigrationRequired:  {waitForSpecChange: true},
+	v1alpha1.ReasonInvalidVariables:   {waitForSpecChange: true},
+	v1alpha1.ReasonUnknownError:       {},
+}
+
+// errorClassifiers are matched in order against the output of a failed
+// Terraform command. The first match determines the failure's class.
+var errorClassifiers = []struct {
+	class   xpv1.ConditionReason
+	pattern *regexp.Regexp
+}{
+	{
+		class:   v1alpha1.ReasonStateLocked,
+		pattern: regexp.MustCompile(`(?i)error acquiring the state lock|state blob is already locked|ConditionalCheckFailedException|lock info:`),
+	},
+	{
+		class:   v1alpha1.ReasonThrottled,
+		pattern: regexp.MustCompile(`(?i)throttl|rate exceeded|rate limit|too many requests|\b429\b|RequestLimitExceeded|SlowDown|quota exceeded`),
+	},
+	{
+		class:   v1alpha1.ReasonAuthFailure,
+		pattern: regexp.MustCompile(`(?i)NoCredentialProviders|no valid credential sources|InvalidClientTokenId|UnrecognizedClientException|ExpiredToken|SignatureDoesNotMatch|AccessDenied|AuthorizationFailed|could not find default credentials|invalid_grant|\b401\b|unauthorized|\b403\b|forbidden`),
+	},
+	{
+		class:   v1alpha1.ReasonNetworkError,
+		pattern: regexp.MustCompile(`(?i)connection refused|connection reset|i/o timeout|TLS handshake timeout|no such host|temporary failure in name resolution|timeout awaiting response headers|unexpected EOF|send request failed|\b50[234]\b|service unavailable|bad gateway`),
+	},
+	{
+		class:   v1alpha1.ReasonConfigError,
+		pattern: regexp.MustCompile(`(?i)unsupported (argument|attribute|block type)|missing required (argument|provider)|invalid (reference|expression|value|resource type|character|block definition)|reference to undeclared|argument or block definition required|no value for required variable|duplicate (resource|variable|output|provider)|variables not allowed|unsupported terraform core version|module not installed|error: invalid`),
+	},
+}
+
+// A classifiedError is an error whose class is known when it is raised,
+// rather than inferred from Terraform's output.
+type classifiedError struct {
+	class xpv1.ConditionReason
+	err   error
+}
+
+func (e classifiedError) Error() string { return e.err.Error() }
+func (e classifiedError) Unwrap() error { return e.err }
+
+// withClass returns the supplied error, classified as the supplied class.
+func withClass(class xpv1.ConditionReason, err error) error {
+	return classifiedError{class: class, err: err}
+}
+
+// classifyError returns the class of the supplied Terraform error.
+func classifyError(err error) xpv1.ConditionReason {
+	var ce classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	for _, c := range errorClassifiers {
+		if c.pattern.MatchString(err.Error()) {
+			return c.class
+		}
+	}
+	return v1alpha1.ReasonUnknownError
+}
+
+// recordFailure classifies the supplied error, records it in the status of
+// the supplied Terraform resource, and schedules its retry according to the
+// class's policy. The error is returned unchanged.
+func (c *TerraformExternal) recordFailure(cr *v1alpha1.Terraform, err error) error {
+	class := classifyError(err)
+
+	f := &v1alpha1.TerraformFailure{
+		Class:                       class,
+		Attempts:                    1,
+		ObservedGeneration:          cr.GetGeneration(),
+		ObservedWorkspaceGeneration: c.workspaceGeneration(),
+	}
+	if last := cr.Status.AtProvider.LastFailure; last != nil && last.Class == class && last.ObservedGeneration == f.ObservedGeneration && last.ObservedWorkspaceGeneration == f.ObservedWorkspaceGeneration {
+		f.Attempts = last.Attempts + 1
+	}
+	if d := retryPolicies[class].delay(f.Attempts); d > 0 {
+		t := metav1.NewTime(time.Now().Add(d))
+		f.RetryAfter = &t
+	}
+
+	cr.Status.AtProvider.LastFailure = f
+	cr.Status.AtProvider.StateLock = nil
+	if class == v1alpha1.ReasonStateLocked {
+		if l := parseLockInfo(err.Error()); l != nil {
+			cr.Status.AtProvider.StateLock = l
+			cr.SetConditions(v1alpha1.TerraformFailed(class, errors.New(lockMessage(l))))
+			return err
+		}
+	}
+	cr.SetConditions(v1alpha1.TerraformFailed(class, err))
+	return err
+}
+
+// recordSuccess clears any failure recorded in the status of the supplied
+// Terraform resource.
+func recordSuccess(cr *v1alpha1.Terraform) {
+	if cr.Status.AtProvider.LastFailure == nil {
+		return
+	}
+	cr.Status.AtProvider.LastFailure = nil
+	cr.Status.AtProvider.StateLock = nil
+	cr.SetConditions(v1alpha1.TerraformSucceeded())
+}
+
+// checkRetry returns an error if the last failure recorded for the supplied
+// Terraform resource should not be retried yet. A change to the Workspace it
+// inherits settings from counts as a change to its spec.
+func (c *TerraformExternal) checkRetry(cr *v1alpha1.Terraform) error {
+	f := cr.Status.AtProvider.LastFailure
+	if f == nil {
+		return nil
+	}
+	if retryPolicies[f.Class].waitForSpecChange && f.ObservedGeneration == cr.GetGeneration() && f.ObservedWorkspaceGeneration == c.workspaceGeneration() {
+		return errors.New(errWaitSpecChange)
+	}
+	if f.RetryAfter != nil && time.Now().Before(f.RetryAfter.Time) {
+		return errors.Errorf(errWaitRetry, f.RetryAfter.Format(time.RFC3339), f.Class)
+	}
+	return nil
+}
+
+// delay returns how long to wait before the supplied retry attempt.
+func (p retryPolicy) delay(attempts int) time.Duration {
+	if p.base == 0 {
+		return 0
+	}
+	d := p.base
+	for i := 1; i < attempts && d < p.max; i++ {
+		d *= 2
+	}
+	if d > p.max {
+		d = p.max
+	}
+	return d
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		err    error
+		want   xpv1.ConditionReason
+	}{
+		"Classified": {
+			reason: "An error classified when it was raised should keep its class, whatever its message.",
+			err:    errors.Wrap(withClass(v1alpha1.ReasonConfigError, errors.New("connection refused")), "cannot apply"),
+			want:   v1alpha1.ReasonConfigError,
+		},
+		"StateLocked": {
+			reason: "A state lock error should be classified as such.",
+			err:    errors.New("Error: Error acquiring the state lock"),
+			want:   v1alpha1.ReasonStateLocked,
+		},
+		"Throttled": {
+			reason: "A rate limit error should be classified as throttling.",
+			err:    errors.New("api error ThrottlingException: Rate exceeded"),
+			want:   v1alpha1.ReasonThrottled,
+		},
+		"AuthFailure": {
+			reason: "An error about missing credentials should be classified as an auth failure.",
+			err:    errors.New("Error: No valid credential sources found"),
+			want:   v1alpha1.ReasonAuthFailure,
+		},
+		"NetworkError": {
+			reason: "A connection error should be classified as a network error.",
+			err:    errors.New("dial tcp 10.0.0.1:443: i/o timeout"),
+			want:   v1alpha1.ReasonNetworkError,
+		},
+		"ConfigError": {
+			reason: "An error in the configuration should be classified as a configuration error.",
+			err:    errors.New(`Error: Unsupported argument: An argument named "nme" is not expected here.`),
+			want:   v1alpha1.ReasonConfigError,
+		},
+		"FirstMatch": {
+			reason: "An error matching several classes should take the class matched first.",
+			err:    errors.New("Error acquiring the state lock: 403 Forbidden"),
+			want:   v1alpha1.ReasonStateLocked,
+		},
+		"Unknown": {
+			reason: "An error matching no class should be unknown.",
+			err:    errors.New("something went wrong"),
+			want:   v1alpha1.ReasonUnknownError,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := classifyError(tc.err)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nclassifyError(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		policy   retryPolicy
+		attempts int
+		want     time.Duration
+	}{
+		"NoBase": {
+			reason:   "A policy without a base delay should retry immediately.",
+			policy:   retryPolicy{},
+			attempts: 3,
+		},
+		"First": {
+			reason:   "The first retry should wait the base delay.",
+			policy:   retryPolicy{base: 10 * time.Second, max: 5 * time.Minute},
+			attempts: 1,
+			want:     10 * time.Second,
+		},
+		"Doubled": {
+			reason:   "Each further retry should wait twice as long.",
+			policy:   retryPolicy{base: 10 * time.Second, max: 5 * time.Minute},
+			attempts: 3,
+			want:     40 * time.Second,
+		},
+		"Capped": {
+			reason:   "No retry should wait longer than the maximum delay.",
+			policy:   retryPolicy{base: 10 * time.Second, max: 5 * time.Minute},
+			attempts: 10,
+			want:     5 * time.Minute,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.policy.delay(tc.attempts)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ndelay(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckRetry(t *testing.T) {
+	past := metav1.NewTime(time.Now().Add(-time.Minute))
+	future := metav1.NewTime(time.Now().Add(time.Hour))
+	ws := &v1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+
+	cases := map[string]struct {
+		reason    string
+		failure   *v1alpha1.TerraformFailure
+		workspace *v1alpha1.Workspace
+		want      bool
+	}{
+		"NoFailure": {
+			reason: "A resource that hasn't failed should not wait.",
+		},
+		"SpecUnchanged": {
+			reason:  "A configuration error should not be retried until the spec changes.",
+			failure: &v1alpha1.TerraformFailure{Class: v1alpha1.ReasonConfigError, ObservedGeneration: 1},
+			want:    true,
+		},
+		"SpecChanged": {
+			reason:  "A configuration error should be retried once the spec changes.",
+			failure: &v1alpha1.TerraformFailure{Class: v1alpha1.ReasonConfigError},
+		},
+		"WorkspaceChanged": {
+			reason:    "A configuration error should be retried once the inherited Workspace changes.",
+			failure:   &v1alpha1.TerraformFailure{Class: v1alpha1.ReasonConfigError, ObservedGeneration: 1, ObservedWorkspaceGeneration: 1},
+			workspace: ws,
+		},
+		"WorkspaceUnchanged": {
+			reason:    "A configuration error should not be retried while the inherited Workspace is unchanged.",
+			failure:   &v1alpha1.TerraformFailure{Class: v1alpha1.ReasonConfigError, ObservedGeneration: 1, ObservedWorkspaceGeneration: 2},
+			workspace: ws,
+			want:      true,
+		},
+		"Waiting": {
+			reason:  "A failure should not be retried before its retry time.",
+			failure: &v1alpha1.TerraformFailure{Class: v1alpha1.ReasonThrottled, ObservedGeneration: 1, RetryAfter: &future},
+			want:    true,
+		},
+		"Due": {
+			reason:  "A failure should be retried after its retry time.",
+			failure: &v1alpha1.TerraformFailure{Class: v1alpha1.ReasonThrottled, ObservedGeneration: 1, RetryAfter: &past},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &TerraformExternal{workspace: tc.workspace}
+			cr := &v1alpha1.Terraform{ObjectMeta: metav1.ObjectMeta{Generation: 1}}
+			cr.Status.AtProvider.LastFailure = tc.failure
+
+			err := c.checkRetry(cr)
+			if got := err != nil; got != tc.want {
+				t.Errorf("\n%s\ncheckRetry(...): want waiting %t, got %v", tc.reason, tc.want, err)
+			}
+		})
+	}
+}
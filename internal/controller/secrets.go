@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfconfig"
+)
+
+const (
+	errGetSecret       = "cannot get Secret %s/%s"
+	errSecretKey       = "Secret %s/%s has no key %q"
+	errNoMemoryFS      = "cannot pass sensitive variables to Terraform without an in-memory filesystem at " + memoryDir
+	errWriteSensitive  = "cannot write sensitive variables"
+	errAuditWorkDir    = "cannot audit working directory"
+	errVariablesSecret = "spec.forProvider.variablesFrom[%d]"
+
+	// memoryDir is a memory backed filesystem. Sensitive variables are only
+	// ever written here, never to the working directory.
+	memoryDir         = "/dev/shm"
+	sensitiveVarsFile = "sensitive.tfvars"
+
+	// Values shorter than this are too likely to match by coincidence, e.g.
+	// "prod" or "true", to be worth auditing for.
+	minAuditLength = 8
+
+	// Larger files, like provider plugins, can't hold the values of
+	// variables.
+	maxAuditSize = 64 << 20
+)
+
+// stateFiles hold Terraform state, which records the values of sensitive
+// variables wherever the configuration uses them. They are reported by an
+// audit, but never removed.
+var stateFiles = map[string]bool{
+	localStateFile:             true,
+	localStateFile + ".backup": true,
+	erroredStateFile:           true,
+}
+
+// secretVariables returns the variables of the supplied Terraform resource
+// that are read from Secrets.
+func (c *TerraformExternal) secretVariables(ctx context.Context, cr *v1alpha1.Terraform) (map[string]string, error) {
+	vars := map[string]string{}
+	for i, v := range cr.Spec.ForProvider.VariablesFrom {
+		if v.SecretKeyRef == nil {
+			continue
+		}
+		val, err := secretKey(ctx, c.kube, *v.SecretKeyRef)
+		if err != nil {
+			return nil, errors.Wrapf(err, errVariablesSecret, i)
+		}
+		vars[v.Name] = val
+	}
+	return vars, nil
+}
+
+// secretKey returns the value of the selected key of a Secret.
+func secretKey(ctx context.Context, kube client.Reader, ref xpv1.SecretKeySelector) (string, error) {
+	s := &corev1.Secret{}
+	if err := kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+		return "", errors.Wrapf(err, errGetSecret, ref.Namespace, ref.Name)
+	}
+	val, ok := s.Data[ref.Key]
+	if !ok {
+		return "", errors.Errorf(errSecretKey, ref.Namespace, ref.Name, ref.Key)
+	}
+	return string(val), nil
+}
+
+// splitSensitive splits the supplied variables into those that may be
+// written to the working directory, and those that must not be: variables
+// read from Secrets, which take precedence over any other value of the same
+// variable, and variables the module declares sensitive.
+func splitSensitive(m *tfconfig.Module, vars, secrets map[string]string) (plain, sensitive map[string]string) {
+	plain = map[string]string{}
+	sensitive = map[string]string{}
+	for k, v := range vars {
+		if m.Sensitive(k) {
+			sensitive[k] = v
+			continue
+		}
+		plain[k] = v
+	}
+	for k, v := range secrets {
+		delete(plain, k)
+		sensitive[k] = v
+	}
+	return plain, sensitive
+}
+
+// writeSensitiveVariables writes the supplied sensitive variables to a file
+// in a directory of their own on the memory backed filesystem, from which
+// they are passed to Terraform with -var-file.
+func (c *TerraformExternal) writeSensitiveVariables(cr *v1alpha1.Terraform, sensitive map[string]string) error {
+	c.sensitive = sensitive
+	if len(sensitive) == 0 {
+		return nil
+	}
+	if fi, err := os.Stat(memoryDir); err != nil || !fi.IsDir() {
+		return errors.New(errNoMemoryFS)
+	}
+
+	dir, err := os.MkdirTemp(memoryDir, fmt.Sprintf("terraform-%s-", cr.GetName()))
+	if err != nil {
+		return errors.Wrap(err, errWriteSensitive)
+	}
+	c.memDir = dir
+
+	keys := make([]string, 0, len(sensitive))
+	for k := range sensitive {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf("%s = %s\n", k, hclString(sensitive[k])))
+	}
+	return errors.Wrap(os.WriteFile(filepath.Join(dir, sensitiveVarsFile), []byte(b.String()), 0600), errWriteSensitive)
+}
+
+// sensitiveVarFile returns the option passing the sensitive variables to
+// Terraform, or nil if there are none.
+func (c *TerraformExternal) sensitiveVarFile() *tfexec.VarFileOption {
+	if c.memDir == "" {
+		return nil
+	}
+	return tfexec.VarFile(filepath.Join(c.memDir, sensitiveVarsFile))
+}
+
+// planOptions returns the supplied options, plus any needed to pass the
+// sensitive variables of the current run.
+func (c *TerraformExternal) planOptions(opts ...tfexec.PlanOption) []tfexec.PlanOption {
+	if vf := c.sensitiveVarFile(); vf != nil {
+		opts = append(opts, vf)
+	}
+	return opts
+}
+
+// destroyOptions returns the supplied options, plus any needed to pass the
+// sensitive variables of the current run.
+func (c *TerraformExternal) destroyOptions(opts ...tfexec.DestroyOption) []tfexec.DestroyOption {
+	if vf := c.sensitiveVarFile(); vf != nil {
+		opts = append(opts, vf)
+	}
+	return opts
+}
+
+// planPath returns where plans are saved. A plan records the values of all
+// variables, so it is saved alongside any sensitive variables rather than
+// in the working directory.
+func (c *TerraformExternal) planPath() string {
+	if c.memDir != "" {
+		return filepath.Join(c.memDir, planFile)
+	}
+	return filepath.Join(c.service.runDir(), planFile)
+}
+
+// scrub removes the sensitive variables of the last run from memory, then
+// audits the working directory for their values, recording the result in
+// the status of the supplied Terraform resource. The audit only reports
+// files holding the values: they may be configuration that the value merely
+// appears in, which Terraform needs.
+func (c *TerraformExternal) scrub(cr *v1alpha1.Terraform) {
+	if c.memDir != "" {
+		_ = os.RemoveAll(c.memDir)
+		c.memDir = ""
+	}
+	switch {
+	case c.sensitive == nil:
+		// The run never got as far as reading its variables.
+		return
+	case len(c.sensitive) == 0:
+		cr.Status.AtProvider.SecretAudit = nil
+		return
+	}
+	if _, err := os.Stat(c.service.workDir); os.IsNotExist(err) {
+		// The working directory was removed by a deletion.
+		return
+	}
+
+	needles := auditValues(c.sensitive)
+	audit := &v1alpha1.SecretAudit{Clean: true}
+	err := filepath.WalkDir(c.service.workDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if fi, err := d.Info(); err != nil || fi.Size() > maxAuditSize {
+			return nil //nolint:nilerr // Files we can't stat can't be audited.
+		}
+		b, err := os.ReadFile(path) //nolint:gosec // Paths are within the working directory.
+		if err != nil {
+			return err
+		}
+		if !containsAny(b, needles) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(c.service.workDir, path)
+		if !stateFiles[filepath.Base(rel)] {
+			audit.Clean = false
+		}
+		audit.Findings = append(audit.Findings, rel)
+		return nil
+	})
+	if err != nil {
+		audit.Clean = false
+		audit.Findings = append(audit.Findings, errors.Wrap(err, errAuditWorkDir).Error())
+	}
+	cr.Status.AtProvider.SecretAudit = audit
+}
+
+// auditValues returns the forms in which the supplied values may appear in
+// files: as is, and escaped as in JSON.
+func auditValues(vars map[string]string) [][]byte {
+	var needles [][]byte
+	for _, v := range vars {
+		if len(v) < minAuditLength {
+			continue
+		}
+		needles = append(needles, []byte(v))
+		if j, err := json.Marshal(v); err == nil && string(j[1:len(j)-1]) != v {
+			needles = append(needles, j[1:len(j)-1])
+		}
+	}
+	return needles
+}
+
+func containsAny(b []byte, needles [][]byte) bool {
+	for _, n := range needles {
+		if bytes.Contains(b, n) {
+			return true
+		}
+	}
+	return false
+}
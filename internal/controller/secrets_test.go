@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfconfig"
+)
+
+func TestSplitSensitive(t *testing.T) {
+	m, diags := tfconfig.RootModule(map[string][]byte{"main.tf": []byte(`
+variable "region" {}
+variable "password" {
+  sensitive = true
+}
+variable "token" {}
+`)}, ".")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	type want struct {
+		plain     map[string]string
+		sensitive map[string]string
+	}
+	cases := map[string]struct {
+		reason  string
+		vars    map[string]string
+		secrets map[string]string
+		want    want
+	}{
+		"Plain": {
+			reason: "Variables that aren't sensitive may be written to the working directory.",
+			vars:   map[string]string{"region": "us-east-1", "undeclared": "x"},
+			want: want{
+				plain:     map[string]string{"region": "us-east-1", "undeclared": "x"},
+				sensitive: map[string]string{},
+			},
+		},
+		"DeclaredSensitive": {
+			reason: "Variables the module declares sensitive must not be written to the working directory.",
+			vars:   map[string]string{"region": "us-east-1", "password": "hunter2"},
+			want: want{
+				plain:     map[string]string{"region": "us-east-1"},
+				sensitive: map[string]string{"password": "hunter2"},
+			},
+		},
+		"FromSecrets": {
+			reason:  "Variables read from Secrets must not be written to the working directory, whether or not they're declared sensitive.",
+			vars:    map[string]string{"region": "us-east-1"},
+			secrets: map[string]string{"token": "t0k3n", "password": "hunter2"},
+			want: want{
+				plain:     map[string]string{"region": "us-east-1"},
+				sensitive: map[string]string{"token": "t0k3n", "password": "hunter2"},
+			},
+		},
+		"SecretOverrides": {
+			reason:  "A variable read from a Secret should take precedence over another value of it, which must not be written either.",
+			vars:    map[string]string{"region": "us-east-1", "token": "default"},
+			secrets: map[string]string{"token": "t0k3n"},
+			want: want{
+				plain:     map[string]string{"region": "us-east-1"},
+				sensitive: map[string]string{"token": "t0k3n"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			plain, sensitive := splitSensitive(m, tc.vars, tc.secrets)
+			got := want{plain: plain, sensitive: sensitive}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nsplitSensitive(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
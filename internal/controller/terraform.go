@@ -3,20 +3,29 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/feature"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/hashicorp/terraform-exec/tfexec"
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfconfig"
 )
 
 const (
@@ -30,16 +39,28 @@ const (
 	errApplyTF      = "cannot apply Terraform"
 	errDestroyTF    = "cannot destroy Terraform"
 	errWriteConfig  = "cannot write Terraform configuration"
+	errShowPlanTF   = "cannot show Terraform plan"
+	errWriteBackend = "cannot write backend configuration"
+
+	planFile = "tfplan"
 )
 
 // A TerraformService manages Terraform configurations.
 type TerraformService struct {
 	workDir string
+
+	// dir is the directory, relative to the working directory, that
+	// Terraform runs in.
+	dir string
 }
 
 // A TerraformConnector is expected to produce a TerraformService when its Connect method
 // is called.
-type TerraformConnector struct{}
+type TerraformConnector struct {
+	kube   client.Client
+	usage  resource.Tracker
+	record event.Recorder
+}
 
 // Connect typically produces an ExternalClient by:
 // 1. Tracking that the managed resource is using a ProviderConfig.
@@ -47,21 +68,74 @@ type TerraformConnector struct{}
 // 3. Getting the credentials specified by the ProviderConfig.
 // 4. Using the credentials to form a client.
 func (c *TerraformConnector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*v1alpha1.Terraform)
+	if !ok {
+		return nil, errors.New(errNotTerraform)
+	}
+	if err := c.usage.Track(ctx, mg); err != nil {
+		return nil, errors.Wrap(err, errTrackPCUsage)
+	}
+	if err := trackAliases(ctx, c.kube, cr); err != nil {
+		return nil, err
+	}
+	ws, err := workspaceFor(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := runDirFor(ws)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a working directory for this Terraform configuration with secure permissions
-	workDir := fmt.Sprintf("/tmp/terraform-%s", mg.GetName())
-	if err := os.MkdirAll(workDir, 0700); err != nil { // Changed from 0755 to 0700
+	s := &TerraformService{workDir: workDirFor(mg.GetName()), dir: dir}
+	if err := os.MkdirAll(s.runDir(), 0700); err != nil { // Changed from 0755 to 0700
 		return nil, errors.Wrap(err, "cannot create working directory")
 	}
 
+	// Read the credentials afresh, so that rotated credentials are used.
+	// Those issued for every read are only read to run Terraform with.
+	creds, err := connectCredentials(ctx, c.kube, mg)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := connectAliases(ctx, c.kube, cr)
+	if err != nil {
+		creds.close()
+		return nil, err
+	}
+
 	return &TerraformExternal{
-		service: &TerraformService{workDir: workDir},
+		kube:      c.kube,
+		record:    c.record,
+		service:   s,
+		workspace: ws,
+		creds:     creds,
+		aliases:   aliases,
 	}, nil
 }
 
 // An TerraformExternal observes, then either creates, updates, or deletes an
 // external resource to ensure it reflects the managed resource's desired state.
 type TerraformExternal struct {
+	kube    client.Client
+	record  event.Recorder
 	service *TerraformService
+
+	// workspace is the Workspace the resource inherits settings from, if
+	// any.
+	workspace *v1alpha1.Workspace
+
+	// creds are the credentials of the resource's ProviderConfig, and
+	// aliases its aliased providers with the credentials of theirs.
+	creds   *credentials
+	aliases []providerAlias
+
+	// sensitive are the variables of the current run that must never be
+	// written to the working directory, and memDir the in-memory directory
+	// they are passed to Terraform from.
+	sensitive map[string]string
+	memDir    string
 }
 
 func (c *TerraformExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -69,32 +143,41 @@ func (c *TerraformExternal) Observe(ctx context.Context, mg resource.Managed) (m
 	if !ok {
 		return managed.ExternalObservation{}, errors.New(errNotTerraform)
 	}
-
-	// Write the Terraform configuration to a file with secure permissions
-	configPath := filepath.Join(c.service.workDir, "main.tf")
-	if err := os.WriteFile(configPath, cr.Spec.ForProvider.Configuration.Raw, 0600); err != nil { // Changed from 0644 to 0600
-		return managed.ExternalObservation{}, errors.Wrap(err, errWriteConfig)
+	// Record the run, so that no lock it acquires is force-unlocked while
+	// it is in flight.
+	done := runs.start()
+	defer done()
+
+	// Don't run Terraform again until the last failure may be retried,
+	// unless the state lock that caused it is to be released, or the state
+	// is to be migrated to a changed backend or workspace. Deleting the
+	// resource doesn't change its spec, so failures that wait for one never
+	// block its deletion.
+	if !meta.WasDeleted(cr) && !forceUnlockRequested(cr) && !migrationPending(cr, c.stateLocation(cr)) {
+		if err := c.checkRetry(cr); err != nil {
+			return managed.ExternalObservation{}, err
+		}
 	}
 
-	// Write backend configuration if specified
-	if err := c.writeBackendConfig(cr); err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, "cannot write backend configuration")
+	if c.observeNeedsCredentials(cr) {
+		if err := c.loadCredentials(ctx); err != nil {
+			return managed.ExternalObservation{}, c.recordFailure(cr, err)
+		}
 	}
 
-	// Write variables configuration if specified
-	if err := c.writeVariablesConfig(cr); err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, "cannot write variables configuration")
+	tf, err := c.setup(ctx, cr)
+	defer c.scrub(cr)
+	if err != nil {
+		return managed.ExternalObservation{}, c.recordFailure(cr, err)
 	}
 
-	// Initialize Terraform executor
-	tf, err := tfexec.NewTerraform(c.service.workDir, "terraform")
-	if err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, errNewClient)
+	if err := c.forceUnlock(ctx, cr, tf); err != nil {
+		return managed.ExternalObservation{}, err
 	}
 
-	// Initialize Terraform
-	if err := tf.Init(ctx); err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, errInitTF)
+	policy := managementPolicies(cr)
+	if policy.ShouldOnlyObserve() {
+		return c.observeOnly(ctx, cr, tf)
 	}
 
 	// Check if the configuration has been applied
@@ -106,13 +189,30 @@ func (c *TerraformExternal) Observe(ctx context.Context, mg resource.Managed) (m
 		}, nil
 	}
 
-	// If we have state, the resource exists
-	resourceExists := state != nil
+	// If anything was applied, the resource exists
+	resourceExists := stateApplied(state)
+	setStateStatus(cr, resourceExists)
+	if !resourceExists {
+		// Report the empty state rather than have it created if the
+		// management policies don't allow that.
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: !policy.ShouldCreate(),
+		}, nil
+	}
+	if !meta.WasDeleted(cr) {
+		recordSuccess(cr)
+	}
+
+	outputs, err := stateOutputs(ctx, tf)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
 
 	return managed.ExternalObservation{
 		ResourceExists:    resourceExists,
 		ResourceUpToDate:  resourceExists, // For now, assume it's up to date if it exists
-		ConnectionDetails: managed.ConnectionDetails{},
+		ConnectionDetails: publishOutputs(cr, outputs),
 	}, nil
 }
 
@@ -121,170 +221,274 @@ func (c *TerraformExternal) Create(ctx context.Context, mg resource.Managed) (ma
 	if !ok {
 		return managed.ExternalCreation{}, errors.New(errNotTerraform)
 	}
+	done := runs.start()
+	defer done()
 
-	// Write the Terraform configuration to a file with secure permissions
-	configPath := filepath.Join(c.service.workDir, "main.tf")
-	if err := os.WriteFile(configPath, cr.Spec.ForProvider.Configuration.Raw, 0600); err != nil { // Changed from 0644 to 0600
-		return managed.ExternalCreation{}, errors.Wrap(err, errWriteConfig)
+	if err := c.loadCredentials(ctx); err != nil {
+		return managed.ExternalCreation{}, c.recordFailure(cr, err)
 	}
-
-	// Write backend configuration if specified
-	if err := c.writeBackendConfig(cr); err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, "cannot write backend configuration")
+	tf, err := c.setup(ctx, cr)
+	defer c.scrub(cr)
+	if err != nil {
+		return managed.ExternalCreation{}, c.recordFailure(cr, err)
 	}
 
-	// Write variables configuration if specified
-	if err := c.writeVariablesConfig(cr); err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, "cannot write variables configuration")
+	if err := c.planAndApply(ctx, cr, tf); err != nil {
+		return managed.ExternalCreation{}, c.recordFailure(cr, err)
 	}
+	recordSuccess(cr)
 
-	// Initialize Terraform executor
-	tf, err := tfexec.NewTerraform(c.service.workDir, "terraform")
-	if err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, errNewClient)
+	return managed.ExternalCreation{
+		ConnectionDetails: managed.ConnectionDetails{},
+	}, nil
+}
+
+func (c *TerraformExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.Terraform)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotTerraform)
 	}
+	done := runs.start()
+	defer done()
 
-	// Initialize Terraform
-	if err := tf.Init(ctx); err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, errInitTF)
+	if cr.Status.AtProvider.State == StateEmpty && !managementPolicies(cr).ShouldCreate() {
+		return managed.ExternalUpdate{}, errors.New(errCreateNotAllowed)
 	}
 
-	// Plan the changes
-	hasChanges, err := tf.Plan(ctx)
+	if err := c.loadCredentials(ctx); err != nil {
+		return managed.ExternalUpdate{}, c.recordFailure(cr, err)
+	}
+	tf, err := c.setup(ctx, cr)
+	defer c.scrub(cr)
 	if err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, errPlanTF)
+		return managed.ExternalUpdate{}, c.recordFailure(cr, err)
 	}
 
-	// Apply the configuration if there are changes
-	if hasChanges {
-		if err := tf.Apply(ctx); err != nil {
-			return managed.ExternalCreation{}, errors.Wrap(err, errApplyTF)
-		}
+	if err := c.planAndApply(ctx, cr, tf); err != nil {
+		return managed.ExternalUpdate{}, c.recordFailure(cr, err)
 	}
+	recordSuccess(cr)
 
-	return managed.ExternalCreation{
+	return managed.ExternalUpdate{
 		ConnectionDetails: managed.ConnectionDetails{},
 	}, nil
 }
 
-func (c *TerraformExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+func (c *TerraformExternal) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
 	cr, ok := mg.(*v1alpha1.Terraform)
 	if !ok {
-		return managed.ExternalUpdate{}, errors.New(errNotTerraform)
+		return managed.ExternalDelete{}, errors.New(errNotTerraform)
 	}
+	done := runs.start()
+	defer done()
 
-	// Write the Terraform configuration to a file with secure permissions
-	configPath := filepath.Join(c.service.workDir, "main.tf")
-	if err := os.WriteFile(configPath, cr.Spec.ForProvider.Configuration.Raw, 0600); err != nil { // Changed from 0644 to 0600
-		return managed.ExternalUpdate{}, errors.Wrap(err, errWriteConfig)
+	if err := checkDeletionProtection(cr); err != nil {
+		return managed.ExternalDelete{}, c.recordFailure(cr, err)
 	}
 
-	// Write backend configuration if specified
-	if err := c.writeBackendConfig(cr); err != nil {
-		return managed.ExternalUpdate{}, errors.Wrap(err, "cannot write backend configuration")
+	if err := c.loadCredentials(ctx); err != nil {
+		return managed.ExternalDelete{}, c.recordFailure(cr, err)
+	}
+	tf, err := c.setup(ctx, cr)
+	defer c.scrub(cr)
+	if err != nil {
+		return managed.ExternalDelete{}, c.recordFailure(cr, err)
 	}
 
-	// Write variables configuration if specified
-	if err := c.writeVariablesConfig(cr); err != nil {
-		return managed.ExternalUpdate{}, errors.Wrap(err, "cannot write variables configuration")
+	// Destroy the configuration
+	if err := c.runCancellable(ctx, cr, tf, func(ctx context.Context) error {
+		return tf.Destroy(ctx, c.destroyOptions(lockTimeout(cr))...)
+	}); err != nil {
+		return managed.ExternalDelete{}, c.recordFailure(cr, errors.Wrap(err, errDestroyTF))
 	}
+	recordSuccess(cr)
 
-	// Initialize Terraform executor
-	tf, err := tfexec.NewTerraform(c.service.workDir, "terraform")
-	if err != nil {
-		return managed.ExternalUpdate{}, errors.Wrap(err, errNewClient)
+	// Clean up the working directory
+	if err := os.RemoveAll(c.service.workDir); err != nil {
+		// Log the error but don't fail the deletion
+		fmt.Printf("Warning: failed to clean up working directory %s: %v\n", c.service.workDir, err)
+	}
+
+	return managed.ExternalDelete{}, nil
+}
+
+// Disconnect removes any files the credentials were written to.
+func (c *TerraformExternal) Disconnect(ctx context.Context) error {
+	c.creds.close()
+	closeAliases(c.aliases)
+	return nil
+}
+
+// setup writes the configuration of the supplied Terraform resource to the
+// working directory and returns an initialized Terraform executor for it.
+func (c *TerraformExternal) setup(ctx context.Context, cr *v1alpha1.Terraform) (*tfexec.Terraform, error) {
+	cr.Status.AtProvider.CredentialLeases = c.credentialLeases()
+
+	// Never plan against a new backend or workspace unless the state is
+	// moved there first.
+	l := c.stateLocation(cr)
+	if err := checkStateLocation(cr, l); err != nil {
+		return nil, err
 	}
 
-	// Initialize Terraform
-	if err := tf.Init(ctx); err != nil {
-		return managed.ExternalUpdate{}, errors.Wrap(err, errInitTF)
+	m, err := readModuleInterface(cr, c.service.dir)
+	if err != nil {
+		return nil, err
 	}
 
-	// Plan the changes
-	hasChanges, err := tf.Plan(ctx)
+	// Variables read from Secrets are read for every run, and never
+	// stored. A missing Secret may yet be created, so is retried.
+	secrets, err := c.secretVariables(ctx, cr)
 	if err != nil {
-		return managed.ExternalUpdate{}, errors.Wrap(err, errPlanTF)
+		return nil, err
 	}
+	vars := c.inheritedVariables(m)
+	maps.Copy(vars, variables(cr))
+	all := maps.Clone(vars)
+	maps.Copy(all, secrets)
 
-	// Apply the configuration if there are changes
-	if hasChanges {
-		if err := tf.Apply(ctx); err != nil {
-			return managed.ExternalUpdate{}, errors.Wrap(err, errApplyTF)
-		}
+	// Catch missing and mistyped variables before running Terraform.
+	if err := checkVariables(m, all); err != nil {
+		return nil, err
 	}
+	recordUndeclared(c.record, cr, m, all)
 
-	return managed.ExternalUpdate{
-		ConnectionDetails: managed.ConnectionDetails{},
-	}, nil
-}
+	// Write the Terraform configuration to a file with secure permissions
+	if err := c.writeConfiguration(cr); err != nil {
+		return nil, errors.Wrap(err, errWriteConfig)
+	}
+	if err := c.writeFiles(cr); err != nil {
+		return nil, errors.Wrap(err, errWriteConfig)
+	}
 
-func (c *TerraformExternal) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
-	cr, ok := mg.(*v1alpha1.Terraform)
-	if !ok {
-		return managed.ExternalDelete{}, errors.New(errNotTerraform)
+	// Write variables to the working directory, unless they're sensitive.
+	plain, sensitive := splitSensitive(m, vars, secrets)
+	if err := c.writeVariablesConfig(plain); err != nil {
+		return nil, errors.Wrap(err, "cannot write variables configuration")
 	}
 
-	// Write the Terraform configuration to a file with secure permissions
-	configPath := filepath.Join(c.service.workDir, "main.tf")
-	if err := os.WriteFile(configPath, cr.Spec.ForProvider.Configuration.Raw, 0600); err != nil { // Changed from 0644 to 0600
-		return managed.ExternalDelete{}, errors.Wrap(err, errWriteConfig)
+	// Configure aliased providers with their own credentials, which are
+	// passed as sensitive variables.
+	aliased, err := c.writeProvidersConfig()
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(sensitive, aliased)
+	if err := c.writeSensitiveVariables(cr, sensitive); err != nil {
+		return nil, err
 	}
 
-	// Write backend configuration if specified
-	if err := c.writeBackendConfig(cr); err != nil {
-		return managed.ExternalDelete{}, errors.Wrap(err, "cannot write backend configuration")
+	// Write import blocks for any resources to adopt
+	if err := c.writeImportsConfig(cr); err != nil {
+		return nil, errors.Wrap(err, "cannot write imports configuration")
 	}
 
-	// Write variables configuration if specified
-	if err := c.writeVariablesConfig(cr); err != nil {
-		return managed.ExternalDelete{}, errors.Wrap(err, "cannot write variables configuration")
+	if migrationPending(cr, l) {
+		if err := c.migrateState(ctx, cr, l); err != nil {
+			return nil, err
+		}
 	}
 
-	// Initialize Terraform executor
-	tf, err := tfexec.NewTerraform(c.service.workDir, "terraform")
+	// Initialize Terraform against the backend and workspace holding the
+	// state.
+	tf, err := c.initAt(ctx, l)
 	if err != nil {
-		return managed.ExternalDelete{}, errors.Wrap(err, errNewClient)
+		return nil, err
 	}
+	cr.Status.AtProvider.StateLocation = &l
 
-	// Initialize Terraform
-	if err := tf.Init(ctx); err != nil {
-		return managed.ExternalDelete{}, errors.Wrap(err, errInitTF)
+	if err := c.checkTerraformVersion(ctx, tf); err != nil {
+		return nil, err
 	}
 
-	// Destroy the configuration
-	if err := tf.Destroy(ctx); err != nil {
-		return managed.ExternalDelete{}, errors.Wrap(err, errDestroyTF)
+	return tf, nil
+}
+
+// planAndApply plans the configuration and applies it if there are changes.
+func (c *TerraformExternal) planAndApply(ctx context.Context, cr *v1alpha1.Terraform, tf *tfexec.Terraform) error {
+	// Plan the changes, saving the plan so that exactly what was inspected
+	// is applied.
+	planPath := c.planPath()
+	defer os.Remove(planPath) //nolint:errcheck // The plan is rewritten by every run.
+
+	hasChanges, err := tf.Plan(ctx, c.planOptions(lockTimeout(cr), tfexec.Out(planPath))...)
+	if err != nil {
+		return errors.Wrap(err, errPlanTF)
 	}
 
-	// Clean up the working directory
-	if err := os.RemoveAll(c.service.workDir); err != nil {
-		// Log the error but don't fail the deletion
-		fmt.Printf("Warning: failed to clean up working directory %s: %v\n", c.service.workDir, err)
+	// Apply the configuration if there are changes
+	if !hasChanges {
+		cr.Status.AtProvider.PendingDestructiveChanges = nil
+		cr.Status.AtProvider.PolicyViolations = nil
+		markImported(cr)
+		return nil
 	}
 
-	return managed.ExternalDelete{}, nil
-}
+	plan, err := tf.ShowPlanFile(ctx, planPath)
+	if err != nil {
+		return errors.Wrap(err, errShowPlanTF)
+	}
+	if err := checkImports(cr, plan); err != nil {
+		return err
+	}
+	acknowledged, err := checkDestructiveChanges(cr, plan)
+	if err != nil {
+		return err
+	}
+	if err := c.checkPlanPolicies(ctx, cr, plan); err != nil {
+		return err
+	}
 
-func (c *TerraformExternal) Disconnect(ctx context.Context) error {
-	// Nothing to disconnect for this implementation
+	if err := c.runCancellable(ctx, cr, tf, func(ctx context.Context) error {
+		return tf.Apply(ctx, lockTimeout(cr), tfexec.DirOrPlan(planPath))
+	}); err != nil {
+		return errors.Wrap(err, errApplyTF)
+	}
+	if acknowledged != "" {
+		cr.Status.AtProvider.AppliedDestructiveChanges = acknowledged
+	}
+	markImported(cr)
 	return nil
 }
 
-// writeBackendConfig writes the Terraform backend configuration to a file
-func (c *TerraformExternal) writeBackendConfig(cr *v1alpha1.Terraform) error {
-	if cr.Spec.ForProvider.Backend == nil {
-		return nil // No backend configuration specified
+// writeConfiguration writes the Terraform configuration to main.tf if it is
+// HCL, or to main.tf.json if it is JSON, removing the other file so that a
+// resource can switch between the two.
+func (c *TerraformExternal) writeConfiguration(cr *v1alpha1.Terraform) error {
+	name, content, err := tfconfig.Main(cr.Spec.ForProvider.Configuration.Raw)
+	if err != nil {
+		return err
 	}
+	for _, f := range []string{tfconfig.MainFile, tfconfig.MainJSONFile} {
+		if f == name {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.service.runDir(), f)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(c.service.runDir(), name), content, 0600)
+}
 
-	backendPath := filepath.Join(c.service.workDir, "backend.tf")
+// writeBackendConfig writes the Terraform backend configuration to a file in
+// the supplied working directory.
+func writeBackendConfig(dir string, b *v1alpha1.BackendConfig) error {
+	backendPath := filepath.Join(dir, "backend.tf")
+	if b == nil {
+		// Fall back to the local backend.
+		if err := os.Remove(backendPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
 
 	// Build backend configuration
 	var backendConfig strings.Builder
-	backendConfig.WriteString(fmt.Sprintf("terraform {\n  backend \"%s\" {\n", cr.Spec.ForProvider.Backend.Type))
+	backendConfig.WriteString(fmt.Sprintf("terraform {\n  backend \"%s\" {\n", b.Type))
 
 	// Add backend configuration parameters
-	for key, value := range cr.Spec.ForProvider.Backend.Configuration {
-		backendConfig.WriteString(fmt.Sprintf("    %s = \"%s\"\n", key, value))
+	for key, value := range b.Configuration {
+		backendConfig.WriteString(fmt.Sprintf("    %s = %s\n", key, hclString(value)))
 	}
 
 	backendConfig.WriteString("  }\n}\n")
@@ -294,38 +498,84 @@ func (c *TerraformExternal) writeBackendConfig(cr *v1alpha1.Terraform) error {
 }
 
 // writeVariablesConfig writes Terraform variables to a tfvars file
-func (c *TerraformExternal) writeVariablesConfig(cr *v1alpha1.Terraform) error {
-	if len(cr.Spec.ForProvider.Variables) == 0 {
-		return nil // No variables specified
+func (c *TerraformExternal) writeVariablesConfig(vars map[string]string) error {
+	varsPath := filepath.Join(c.service.runDir(), "terraform.tfvars")
+
+	if len(vars) == 0 {
+		// No variables specified
+		if err := os.Remove(varsPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
 	}
 
-	varsPath := filepath.Join(c.service.workDir, "terraform.tfvars")
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
 	var varsConfig strings.Builder
-	for key, value := range cr.Spec.ForProvider.Variables {
-		varsConfig.WriteString(fmt.Sprintf("%s = \"%s\"\n", key, value))
+	for _, key := range keys {
+		varsConfig.WriteString(fmt.Sprintf("%s = %s\n", key, hclString(vars[key])))
 	}
 
 	// Write variables with secure permissions
 	return os.WriteFile(varsPath, []byte(varsConfig.String()), 0600)
 }
 
+// TerraformOptions configure the Terraform controller.
+type TerraformOptions struct {
+	// VariableSources are the kinds of Kubernetes objects whose fields
+	// variables may be set to.
+	VariableSources []schema.GroupVersionKind
+}
+
 // SetupTerraform adds a controller that reconciles Terraform managed resources.
-func SetupTerraform(mgr ctrl.Manager, o controller.Options) error {
+func SetupTerraform(mgr ctrl.Manager, o controller.Options, to TerraformOptions) error {
 	name := managed.ControllerName(v1alpha1.TerraformGroupKind.Kind)
 
 	cps := []managed.ConnectionPublisher{managed.NewAPISecretPublisher(mgr.GetClient(), mgr.GetScheme())}
-
-	r := managed.NewReconciler(mgr,
-		resource.ManagedKind(v1alpha1.TerraformGroupVersionKind),
-		managed.WithExternalConnecter(&TerraformConnector{}),
+	record := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
+
+	watches := &sourceWatches{
+		started: map[schema.GroupVersionKind]bool{},
+		cache:   mgr.GetCache(),
+		kube:    mgr.GetClient(),
+	}
+	allowed := make(map[schema.GroupVersionKind]bool, len(to.VariableSources))
+	for _, gvk := range to.VariableSources {
+		allowed[gvk] = true
+	}
+
+	opts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&TerraformConnector{
+			kube:   mgr.GetClient(),
+			usage:  resource.NewProviderConfigUsageTracker(mgr.GetClient(), &v1alpha1.ProviderConfigUsage{}),
+			record: record,
+		}),
+		managed.WithReferenceResolver(&variableResolver{client: mgr.GetClient(), allowed: allowed, watches: watches}),
+		managed.WithFinalizer(workDirFinalizer{resource.NewAPIFinalizer(mgr.GetClient(), managed.FinalizerName)}),
 		managed.WithLogger(o.Logger.WithValues("controller", name)),
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
-		managed.WithConnectionPublishers(cps...))
+		managed.WithRecorder(record),
+		managed.WithConnectionPublishers(cps...),
+	}
+	if o.Features.Enabled(feature.EnableBetaManagementPolicies) {
+		opts = append(opts, managed.WithManagementPolicies())
+	}
 
-	return ctrl.NewControllerManagedBy(mgr).
+	r := managed.NewReconciler(mgr, resource.ManagedKind(v1alpha1.TerraformGroupVersionKind), opts...)
+
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		WithOptions(o.ForControllerRuntime()).
 		For(&v1alpha1.Terraform{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+		Watches(&v1alpha1.Terraform{}, enqueueDependents(mgr.GetClient()), builder.WithPredicates(outputsChanged)).
+		Watches(&v1alpha1.Workspace{}, enqueueWorkspaceDependents(mgr.GetClient()), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Build(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+	if err != nil {
+		return err
+	}
+	watches.controller = c
+	return nil
 }
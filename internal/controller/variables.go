@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"maps"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+// variables returns the Terraform variables of the supplied resource: those
+// set explicitly, and those set to the outputs of other Terraform resources.
+func variables(cr *v1alpha1.Terraform) map[string]string {
+	vars := make(map[string]string, len(cr.Spec.ForProvider.Variables)+len(cr.Spec.ForProvider.VariablesFrom))
+	for k, v := range cr.Spec.ForProvider.Variables {
+		vars[k] = v
+	}
+	for _, v := range cr.Spec.ForProvider.VariablesFrom {
+		// Outputs are resolved before the resource is connected to, so an
+		// unresolved output means reference resolution was skipped, e.g.
+		// because the resource is only observed.
+		if v.Value != "" {
+			vars[v.Name] = v.Value
+		}
+	}
+	return vars
+}
+
+// enqueueDependents enqueues the Terraform resources with variables set to
+// the outputs of a Terraform resource.
+func enqueueDependents(kube client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		l := &v1alpha1.TerraformList{}
+		if err := kube.List(ctx, l); err != nil {
+			return nil
+		}
+		var reqs []reconcile.Request
+		for _, cr := range l.Items {
+			for _, v := range cr.Spec.ForProvider.VariablesFrom {
+				if v.TerraformRef != nil && v.TerraformRef.Name == obj.GetName() {
+					reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Name: cr.GetName()}})
+					break
+				}
+			}
+		}
+		return reqs
+	})
+}
+
+// outputsChanged accepts updates that change a Terraform resource's outputs.
+var outputsChanged = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		o, ok := e.ObjectOld.(*v1alpha1.Terraform)
+		if !ok {
+			return false
+		}
+		n, ok := e.ObjectNew.(*v1alpha1.Terraform)
+		if !ok {
+			return false
+		}
+		return !maps.Equal(o.Status.AtProvider.Outputs, n.Status.AtProvider.Outputs)
+	},
+}
@@ -0,0 +1,284 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/validation"
+	"github.com/mgeorge67701/crossplane-terraform/internal/vault"
+)
+
+const (
+	errVaultCABundle = "cannot get Vault CA bundle"
+	errVaultClient   = "cannot create Vault client"
+	errVaultLogin    = "cannot log in to Vault"
+	errVaultRead     = "cannot read %s from Vault"
+	errUnknownEngine = "unknown secrets engine %s"
+	errVaultGCPKey   = "cannot decode service account key"
+
+	// vaultTokenExpiration is the lifetime of the service account token the
+	// provider logs in with. It is only used to log in, so is the shortest
+	// Kubernetes allows.
+	vaultTokenExpiration = int64(600)
+
+	vaultRevokeTimeout = 30 * time.Second
+)
+
+// A vaultEngine translates the data of a secret read from a secrets engine
+// into the environment variables and files a cloud's Terraform provider
+// reads, adding the variables to env. Files are written with c.writeFile.
+type vaultEngine func(c *credentials, s *v1alpha1.VaultSecret, name string, data map[string]any, env map[string]string) error
+
+// vaultEngines are the translations of each secrets engine's secrets.
+var vaultEngines = map[v1alpha1.VaultSecretEngine]vaultEngine{
+	v1alpha1.VaultSecretAWS:   vaultAWS,
+	v1alpha1.VaultSecretGCP:   vaultGCP,
+	v1alpha1.VaultSecretAzure: vaultAzure,
+	v1alpha1.VaultSecretKV:    vaultKV,
+}
+
+// readVault logs in to Vault and reads the supplied secrets, recording their
+// leases. The leases, and the Vault token they were issued to, are revoked
+// when the credentials are closed.
+func (c *credentials) readVault(ctx context.Context, kube client.Client, name string, v *v1alpha1.VaultCredentials) error {
+	api, err := loginVault(ctx, kube, v)
+	if err != nil {
+		return err
+	}
+	c.release = append(c.release, func() { revokeVault(api, c.leases) })
+
+	for i := range v.Secrets {
+		s := &v.Secrets[i]
+		fn, ok := vaultEngines[s.Engine]
+		if !ok {
+			return errors.Wrapf(errors.Errorf(errUnknownEngine, s.Engine), errVaultRead, s.Path)
+		}
+		params := url.Values{}
+		if s.TTL != nil {
+			params.Set("ttl", fmt.Sprintf("%ds", int64(s.TTL.Seconds())))
+		}
+		secret, err := api.Read(ctx, s.Path, params)
+		if err != nil {
+			return errors.Wrapf(err, errVaultRead, s.Path)
+		}
+		l := v1alpha1.CredentialLease{Path: s.Path, LeaseID: secret.LeaseID, Renewable: secret.Renewable}
+		if secret.LeaseDuration > 0 {
+			l.TTL = &metav1.Duration{Duration: time.Duration(secret.LeaseDuration) * time.Second}
+		}
+		// Record the lease before anything else can fail, so it's revoked.
+		c.leases = append(c.leases, l)
+
+		env := map[string]string{}
+		if err := fn(c, s, name, secret.Data, env); err != nil {
+			return errors.Wrapf(err, errVaultRead, s.Path)
+		}
+		c.leases[len(c.leases)-1].Variables = slices.Sorted(maps.Keys(env))
+		maps.Copy(c.env, env)
+	}
+	return nil
+}
+
+// loginVault returns a client of the supplied Vault server, logged in with a
+// token of the configured service account.
+func loginVault(ctx context.Context, kube client.Client, v *v1alpha1.VaultCredentials) (*vault.Client, error) {
+	var ca []byte
+	if ref := v.CABundleSecretRef; ref != nil {
+		b, err := secretKey(ctx, kube, *ref)
+		if err != nil {
+			return nil, errors.Wrap(err, errVaultCABundle)
+		}
+		ca = []byte(b)
+	}
+	api, err := vault.NewClient(v.Address, v.Namespace, ca)
+	if err != nil {
+		return nil, errors.Wrap(err, errVaultClient)
+	}
+
+	req, err := newTokenRequester(kube, v.Auth.ServiceAccountRef, v.Auth.Audience, vaultTokenExpiration)
+	if err != nil {
+		return nil, errors.Wrap(err, errVaultLogin)
+	}
+	tr, err := req(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errVaultLogin)
+	}
+	return api, errors.Wrap(api.LoginKubernetes(ctx, v.Auth.MountPath, v.Auth.Role, tr.Status.Token), errVaultLogin)
+}
+
+// checkVault checks that the provider can log in to the supplied Vault
+// server, without reading any secrets: reading dynamic credentials issues
+// them.
+func checkVault(ctx context.Context, kube client.Client, v *v1alpha1.VaultCredentials) error {
+	api, err := loginVault(ctx, kube, v)
+	if err != nil {
+		return err
+	}
+	revokeVault(api, nil)
+	return nil
+}
+
+// revokeVault revokes the supplied leases, then the Vault token they were
+// issued to. Revoking the token revokes any leases it was issued too, and
+// leases that can't be revoked expire once their TTL has passed, so errors
+// are ignored.
+func revokeVault(api *vault.Client, leases []v1alpha1.CredentialLease) {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultRevokeTimeout)
+	defer cancel()
+	for _, l := range leases {
+		if l.LeaseID != "" {
+			_ = api.Revoke(ctx, l.LeaseID)
+		}
+	}
+	_ = api.RevokeSelf(ctx)
+}
+
+// credentialLeases returns the leases on the credentials of the resource's
+// ProviderConfig, and on those of its aliased providers.
+func (c *TerraformExternal) credentialLeases() []v1alpha1.CredentialLease {
+	var leases []v1alpha1.CredentialLease
+	if c.creds != nil {
+		leases = append(leases, c.creds.leases...)
+	}
+	for _, a := range c.aliases {
+		leases = append(leases, a.creds.leases...)
+	}
+	return leases
+}
+
+// loadCredentials reads the credentials of the resource's ProviderConfig, and
+// of those of its aliased providers, that are only read when Terraform is to
+// be run with them.
+func (c *TerraformExternal) loadCredentials(ctx context.Context) error {
+	if err := c.creds.load(ctx); err != nil {
+		return errors.Wrap(err, errGetCreds)
+	}
+	for _, a := range c.aliases {
+		if err := a.creds.load(ctx); err != nil {
+			return errors.Wrapf(err, errGetAliasCreds, a.Provider, a.Alias)
+		}
+	}
+	return nil
+}
+
+// observeNeedsCredentials returns true if observing the supplied resource
+// runs Terraform with more than its local state: to plan an observe-only
+// resource, or to read its state from, or move it off, a backend. Showing
+// local state needs no credentials, so none are issued for it.
+func (c *TerraformExternal) observeNeedsCredentials(cr *v1alpha1.Terraform) bool {
+	if managementPolicies(cr).ShouldOnlyObserve() || c.stateLocation(cr).Backend != nil {
+		return true
+	}
+	last := cr.Status.AtProvider.StateLocation
+	return last != nil && last.Backend != nil
+}
+
+// vaultAWS translates credentials of the AWS secrets engine. Those of an IAM
+// user have no session token.
+func vaultAWS(_ *credentials, _ *v1alpha1.VaultSecret, _ string, data map[string]any, env map[string]string) error {
+	keys := vaultStrings(data)
+	if err := requireKeys(keys, "access_key", "secret_key"); err != nil {
+		return err
+	}
+	env["AWS_ACCESS_KEY_ID"] = keys["access_key"]
+	env["AWS_SECRET_ACCESS_KEY"] = keys["secret_key"]
+	if v := keys["security_token"]; v != "" {
+		env["AWS_SESSION_TOKEN"] = v
+	}
+	if v := keys["session_token"]; v != "" {
+		env["AWS_SESSION_TOKEN"] = v
+	}
+	return nil
+}
+
+// vaultGCP translates a service account key, or an OAuth access token, of
+// the Google Cloud secrets engine.
+func vaultGCP(c *credentials, _ *v1alpha1.VaultSecret, name string, data map[string]any, env map[string]string) error {
+	keys := vaultStrings(data)
+	if v := keys["token"]; v != "" {
+		env["GOOGLE_OAUTH_ACCESS_TOKEN"] = v
+		return nil
+	}
+	if err := requireKeys(keys, "private_key_data"); err != nil {
+		return err
+	}
+	key, err := base64.StdEncoding.DecodeString(keys["private_key_data"])
+	if err != nil {
+		return errors.Wrap(err, errVaultGCPKey)
+	}
+	path, err := c.writeFile(name, gcpCredentialsFile, key)
+	if err != nil {
+		return err
+	}
+	env["GOOGLE_APPLICATION_CREDENTIALS"] = path
+	return nil
+}
+
+// vaultAzure translates service principal credentials of the Azure secrets
+// engine. The tenant and subscription are set by the environment.
+func vaultAzure(_ *credentials, _ *v1alpha1.VaultSecret, _ string, data map[string]any, env map[string]string) error {
+	keys := vaultStrings(data)
+	if err := requireKeys(keys, "client_id", "client_secret"); err != nil {
+		return err
+	}
+	env["ARM_CLIENT_ID"] = keys["client_id"]
+	env["ARM_CLIENT_SECRET"] = keys["client_secret"]
+	return nil
+}
+
+// vaultKV translates the values of a KV secret, of either version, into the
+// variables its keys are mapped to, or are named after.
+func vaultKV(_ *credentials, s *v1alpha1.VaultSecret, _ string, data map[string]any, env map[string]string) error {
+	// The values of a version 2 secret are nested in its data, along with
+	// their metadata.
+	if d, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = d
+		}
+	}
+	keys := vaultStrings(data)
+	if len(s.Env) > 0 {
+		for k, v := range s.Env {
+			if _, ok := keys[k]; !ok {
+				return errors.Errorf(errMissingKeys, k)
+			}
+			env[v] = keys[k]
+		}
+		return nil
+	}
+	for k, v := range keys {
+		if !validation.EnvVarName.MatchString(k) {
+			return errors.Errorf(errNotEnvVarName, k)
+		}
+		env[k] = v
+	}
+	return nil
+}
+
+// vaultStrings returns the values of the supplied secret data as strings.
+// Values that aren't strings are JSON encoded, and null values omitted.
+func vaultStrings(data map[string]any) map[string]string {
+	keys := make(map[string]string, len(data))
+	for k, v := range data {
+		switch s := v.(type) {
+		case nil:
+			continue
+		case string:
+			keys[k] = s
+			continue
+		}
+		b, _ := json.Marshal(v) //nolint:errchkjson // Decoded JSON can be encoded.
+		keys[k] = string(b)
+	}
+	return keys
+}
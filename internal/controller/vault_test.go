@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+// A vaultStandIn is a stand-in for a Vault server that issues leased AWS
+// credentials to a role of its Kubernetes auth method, and records the
+// requests it was sent.
+type vaultStandIn struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (v *vaultStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	v.requests = append(v.requests, r.Method+" "+r.URL.Path)
+	v.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/auth/kubernetes/login":
+		_ = json.NewEncoder(w).Encode(map[string]any{"auth": map[string]any{"client_token": "vault-token"}})
+	case "/v1/aws/creds/deploy":
+		if r.Header.Get("X-Vault-Token") != "vault-token" || r.URL.Query().Get("ttl") != "900s" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"lease_id":       "aws/creds/deploy/abc",
+			"lease_duration": 900,
+			"data":           map[string]any{"access_key": "AKIA", "secret_key": "secret"},
+		})
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestVaultCredentials(t *testing.T) {
+	v := &vaultStandIn{}
+	srv := httptest.NewServer(v)
+	defer srv.Close()
+
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "terraform", Namespace: "crossplane-system"}}
+	kube := fake.NewClientBuilder().WithObjects(sa).Build()
+	pc := &v1alpha1.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: "vault"}}
+	pc.Spec.Credentials.Source = v1alpha1.CredentialsSourceVault
+	pc.Spec.Credentials.Vault = &v1alpha1.VaultCredentials{
+		Address: srv.URL,
+		Auth: v1alpha1.VaultKubernetesAuth{
+			Role:              "deploy",
+			ServiceAccountRef: &v1alpha1.ServiceAccountReference{Name: sa.Name, Namespace: sa.Namespace},
+		},
+		Secrets: []v1alpha1.VaultSecret{{Engine: v1alpha1.VaultSecretAWS, Path: "aws/creds/deploy", TTL: &metav1.Duration{Duration: 15 * time.Minute}}},
+	}
+
+	ctx := context.Background()
+	c, err := newCredentials(ctx, kube, pc)
+	if err != nil {
+		t.Fatalf("newCredentials(...): %v", err)
+	}
+	if len(v.requests) != 0 {
+		t.Errorf("newCredentials(...): want nothing read from Vault until the credentials are loaded, got %v", v.requests)
+	}
+
+	for range 2 {
+		if err := c.load(ctx); err != nil {
+			t.Fatalf("load(...): %v", err)
+		}
+	}
+	wantEnv := map[string]string{"AWS_ACCESS_KEY_ID": "AKIA", "AWS_SECRET_ACCESS_KEY": "secret"}
+	if diff := cmp.Diff(wantEnv, c.env); diff != "" {
+		t.Errorf("load(...): -want env, +got:\n%s", diff)
+	}
+	wantLeases := []v1alpha1.CredentialLease{{
+		Path:      "aws/creds/deploy",
+		LeaseID:   "aws/creds/deploy/abc",
+		TTL:       &metav1.Duration{Duration: 15 * time.Minute},
+		Variables: []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+	}}
+	if diff := cmp.Diff(wantLeases, c.leases); diff != "" {
+		t.Errorf("load(...): -want leases, +got:\n%s", diff)
+	}
+
+	c.close()
+	want := []string{
+		"POST /v1/auth/kubernetes/login",
+		"GET /v1/aws/creds/deploy",
+		"PUT /v1/sys/leases/revoke",
+		"POST /v1/auth/token/revoke-self",
+	}
+	if diff := cmp.Diff(want, v.requests); diff != "" {
+		t.Errorf("-want requests to Vault, +got:\n%s", diff)
+	}
+}
+
+func TestVaultKV(t *testing.T) {
+	type want struct {
+		env map[string]string
+		err bool
+	}
+	cases := map[string]struct {
+		reason string
+		s      *v1alpha1.VaultSecret
+		data   map[string]any
+		want   want
+	}{
+		"Version1": {
+			reason: "The keys of a version 1 secret should be the variables set.",
+			s:      &v1alpha1.VaultSecret{},
+			data:   map[string]any{"TF_VAR_token": "secret", "DEBUG": true},
+			want:   want{env: map[string]string{"TF_VAR_token": "secret", "DEBUG": "true"}},
+		},
+		"Version2": {
+			reason: "The values of a version 2 secret should be unwrapped from its data and metadata.",
+			s:      &v1alpha1.VaultSecret{},
+			data: map[string]any{
+				"data":     map[string]any{"TF_VAR_token": "secret"},
+				"metadata": map[string]any{"version": 3},
+			},
+			want: want{env: map[string]string{"TF_VAR_token": "secret"}},
+		},
+		"Version1KeyNamedData": {
+			reason: "A version 1 secret with a key named data, but no metadata, should not be unwrapped.",
+			s:      &v1alpha1.VaultSecret{Env: map[string]string{"data": "TF_VAR_data"}},
+			data:   map[string]any{"data": map[string]any{"a": "b"}},
+			want:   want{env: map[string]string{"TF_VAR_data": `{"a":"b"}`}},
+		},
+		"Mapped": {
+			reason: "Only the mapped keys should be set, as the variables they are mapped to.",
+			s:      &v1alpha1.VaultSecret{Env: map[string]string{"token": "TF_VAR_token"}},
+			data: map[string]any{
+				"data":     map[string]any{"token": "secret", "unused": "value"},
+				"metadata": map[string]any{},
+			},
+			want: want{env: map[string]string{"TF_VAR_token": "secret"}},
+		},
+		"MissingKey": {
+			reason: "A mapped key the secret doesn't have should be an error.",
+			s:      &v1alpha1.VaultSecret{Env: map[string]string{"token": "TF_VAR_token"}},
+			data:   map[string]any{"other": "value"},
+			want:   want{env: map[string]string{}, err: true},
+		},
+		"NullValue": {
+			reason: "A mapped key with a null value should be treated as missing.",
+			s:      &v1alpha1.VaultSecret{Env: map[string]string{"token": "TF_VAR_token"}},
+			data:   map[string]any{"token": nil},
+			want:   want{env: map[string]string{}, err: true},
+		},
+		"NotEnvVarName": {
+			reason: "Unmapped keys that aren't environment variable names should be an error.",
+			s:      &v1alpha1.VaultSecret{},
+			data:   map[string]any{"api-token": "secret"},
+			want:   want{env: map[string]string{}, err: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			env := map[string]string{}
+			err := vaultKV(nil, tc.s, "pc", tc.data, env)
+			if (err != nil) != tc.want.err {
+				t.Errorf("\n%s\nvaultKV(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if diff := cmp.Diff(tc.want.env, env); diff != "" {
+				t.Errorf("\n%s\nvaultKV(...): -want env, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestVaultAWS(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		data   map[string]any
+		want   map[string]string
+		err    bool
+	}{
+		"IAMUser": {
+			reason: "Credentials of an IAM user should have no session token.",
+			data:   map[string]any{"access_key": "AKIA", "secret_key": "secret", "security_token": nil},
+			want:   map[string]string{"AWS_ACCESS_KEY_ID": "AKIA", "AWS_SECRET_ACCESS_KEY": "secret"},
+		},
+		"AssumedRole": {
+			reason: "Credentials of an assumed role should have a session token.",
+			data:   map[string]any{"access_key": "ASIA", "secret_key": "secret", "session_token": "session"},
+			want:   map[string]string{"AWS_ACCESS_KEY_ID": "ASIA", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_SESSION_TOKEN": "session"},
+		},
+		"Incomplete": {
+			reason: "Credentials without a secret key should be an error.",
+			data:   map[string]any{"access_key": "AKIA"},
+			want:   map[string]string{},
+			err:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			env := map[string]string{}
+			err := vaultAWS(nil, &v1alpha1.VaultSecret{}, "pc", tc.data, env)
+			if (err != nil) != tc.err {
+				t.Errorf("\n%s\nvaultAWS(...): want error %t, got %v", tc.reason, tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, env); diff != "" {
+				t.Errorf("\n%s\nvaultAWS(...): -want env, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
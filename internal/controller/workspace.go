@@ -2,43 +2,106 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/terraform-exec/tfexec"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfe"
 )
 
 const (
-	errNotWorkspace = "managed resource is not a Workspace custom resource"
+	errNotWorkspace         = "managed resource is not a Workspace custom resource"
+	errListWorkspaces       = "cannot list Terraform workspaces"
+	errCreateWorkspace      = "cannot create Terraform workspace"
+	errDeleteWorkspace      = "cannot delete Terraform workspace"
+	errDestroyWorkspace     = "cannot destroy the resources of Terraform workspace"
+	errReadWorkspaceState   = "cannot read state of Terraform workspace"
+	errSetEnvironment       = "cannot set Terraform environment"
+	errWorkspaceHasResource = "workspace %q still manages %d resources; destroy them, or set autoDestroy to destroy them when the workspace is deleted"
+
+	// WorkspaceAvailable is the status of a workspace that exists.
+	WorkspaceAvailable = "Available"
 )
 
-// A WorkspaceService does nothing.
-type WorkspaceService struct{}
+// workspaceDirFor returns the working directory of the named Workspace
+// resource. Resource names can't contain underscores, so it is never that of
+// a Terraform resource.
+func workspaceDirFor(name string) string {
+	return fmt.Sprintf("/tmp/terraform_workspaces/%s", name)
+}
 
-// A WorkspaceConnector is expected to produce a WorkspaceService when its Connect method
-// is called.
-type WorkspaceConnector struct{}
+// A WorkspaceService manages Terraform workspaces from a working directory
+// initialised against their backend.
+type WorkspaceService struct {
+	workDir string
+}
+
+// A WorkspaceConnector produces a WorkspaceService for a Workspace resource
+// when its Connect method is called.
+type WorkspaceConnector struct {
+	kube  client.Client
+	usage resource.Tracker
+}
 
-// Connect typically produces an ExternalClient by:
-// 1. Tracking that the managed resource is using a ProviderConfig.
-// 2. Getting the managed resource's ProviderConfig.
-// 3. Getting the credentials specified by the ProviderConfig.
-// 4. Using the credentials to form a client.
+// Connect creates the working directory of the supplied Workspace resource,
+// or a client of the API managing it if it is a remote workspace.
 func (c *WorkspaceConnector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
-	return &WorkspaceExternal{service: &WorkspaceService{}}, nil
+	cr, ok := mg.(*v1alpha1.Workspace)
+	if !ok {
+		return nil, errors.New(errNotWorkspace)
+	}
+	if err := c.usage.Track(ctx, mg); err != nil {
+		return nil, errors.Wrap(err, errTrackPCUsage)
+	}
+	if r := cr.Spec.ForProvider.Remote; r != nil {
+		token, err := secretKey(ctx, c.kube, r.TokenSecretRef)
+		if err != nil {
+			return nil, errors.Wrap(err, errGetRemoteToken)
+		}
+		api, err := tfe.NewClient(r.Address, token)
+		if err != nil {
+			return nil, err
+		}
+		return &WorkspaceExternal{remote: api}, nil
+	}
+
+	workDir := workspaceDirFor(mg.GetName())
+	if err := os.MkdirAll(workDir, 0700); err != nil {
+		return nil, errors.Wrap(err, "cannot create working directory")
+	}
+	creds, err := connectCredentials(ctx, c.kube, mg)
+	if err != nil {
+		return nil, err
+	}
+	return &WorkspaceExternal{service: &WorkspaceService{workDir: workDir}, creds: creds}, nil
 }
 
-// An WorkspaceExternal observes, then either creates, updates, or deletes an
-// external resource to ensure it reflects the managed resource's desired state.
+// A WorkspaceExternal observes, then either creates, updates or deletes a
+// Terraform workspace to ensure it reflects the Workspace resource's desired
+// state. Remote workspaces are managed through the API, and any other through
+// the Terraform CLI.
 type WorkspaceExternal struct {
 	service *WorkspaceService
+	remote  *tfe.Client
+
+	// creds are the credentials of the resource's ProviderConfig, used to
+	// reach the backend.
+	creds *credentials
 }
 
 func (c *WorkspaceExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -46,24 +109,37 @@ func (c *WorkspaceExternal) Observe(ctx context.Context, mg resource.Managed) (m
 	if !ok {
 		return managed.ExternalObservation{}, errors.New(errNotWorkspace)
 	}
+	if c.remote != nil {
+		return c.observeRemote(ctx, cr)
+	}
+
+	tf, err := c.setup(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	exists, err := workspaceExists(ctx, tf, cr.Spec.ForProvider.Name)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if !exists {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
 
-	// These fmt statements should be removed in the real implementation.
-	fmt.Printf("Observing: %+v", cr)
+	if err := tf.WorkspaceSelect(ctx, cr.Spec.ForProvider.Name); err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errReadWorkspaceState)
+	}
+	s, err := pullWorkspaceState(ctx, tf)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	observeWorkspace(cr, s)
 
+	// There is nothing to update: a workspace on a backend is only its name,
+	// and its state is managed by the Terraform resources using it.
 	return managed.ExternalObservation{
-		// Return false when the external resource does not exist. This lets
-		// the managed resource reconciler know that it needs to call Create to
-		// (re)create the resource, or that it has successfully been deleted.
-		ResourceExists: true,
-
-		// Return false when the external resource exists, but it not up to date
-		// with the desired managed resource state. This lets the managed
-		// resource reconciler know that it needs to call Update.
+		ResourceExists:   true,
 		ResourceUpToDate: true,
-
-		// Return any details that may be required to connect to the external
-		// resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
 	}, nil
 }
 
@@ -72,14 +148,21 @@ func (c *WorkspaceExternal) Create(ctx context.Context, mg resource.Managed) (ma
 	if !ok {
 		return managed.ExternalCreation{}, errors.New(errNotWorkspace)
 	}
+	if c.remote != nil {
+		return c.createRemote(ctx, cr)
+	}
 
-	fmt.Printf("Creating: %+v", cr)
+	tf, err := c.setup(ctx, cr)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if err := tf.WorkspaceNew(ctx, cr.Spec.ForProvider.Name); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errCreateWorkspace)
+	}
+	now := metav1.Now()
+	cr.Status.AtProvider.CreatedAt = &now
 
-	return managed.ExternalCreation{
-		// Optionally return any details that may be required to connect to the
-		// external resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
-	}, nil
+	return managed.ExternalCreation{}, nil
 }
 
 func (c *WorkspaceExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
@@ -87,14 +170,11 @@ func (c *WorkspaceExternal) Update(ctx context.Context, mg resource.Managed) (ma
 	if !ok {
 		return managed.ExternalUpdate{}, errors.New(errNotWorkspace)
 	}
-
-	fmt.Printf("Updating: %+v", cr)
-
-	return managed.ExternalUpdate{
-		// Optionally return any details that may be required to connect to the
-		// external resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
-	}, nil
+	if c.remote != nil {
+		return c.updateRemote(ctx, cr)
+	}
+	// Workspaces on a backend are always up to date once they exist.
+	return managed.ExternalUpdate{}, nil
 }
 
 func (c *WorkspaceExternal) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
@@ -102,17 +182,176 @@ func (c *WorkspaceExternal) Delete(ctx context.Context, mg resource.Managed) (ma
 	if !ok {
 		return managed.ExternalDelete{}, errors.New(errNotWorkspace)
 	}
+	if c.remote != nil {
+		return c.deleteRemote(ctx, cr)
+	}
 
-	fmt.Printf("Deleting: %+v", cr)
+	name := cr.Spec.ForProvider.Name
+	if name == v1alpha1.DefaultWorkspace {
+		// Terraform never deletes the default workspace.
+		return managed.ExternalDelete{}, errors.Wrap(os.RemoveAll(c.service.workDir), errDeleteWorkspace)
+	}
+	if n := cr.Status.AtProvider.ResourceCount; n > 0 && !cr.Spec.ForProvider.AutoDestroy {
+		return managed.ExternalDelete{}, errors.Errorf(errWorkspaceHasResource, name, n)
+	}
 
-	return managed.ExternalDelete{}, nil
+	tf, err := c.setup(ctx, cr)
+	if err != nil {
+		return managed.ExternalDelete{}, err
+	}
+	if cr.Status.AtProvider.ResourceCount > 0 {
+		if err := destroyWorkspace(ctx, tf, name); err != nil {
+			return managed.ExternalDelete{}, err
+		}
+	}
+	// Terraform won't delete the selected workspace, nor one whose state
+	// still manages resources.
+	if err := tf.WorkspaceSelect(ctx, v1alpha1.DefaultWorkspace); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteWorkspace)
+	}
+	if err := tf.WorkspaceDelete(ctx, name); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteWorkspace)
+	}
+	return managed.ExternalDelete{}, errors.Wrap(os.RemoveAll(c.service.workDir), errDeleteWorkspace)
 }
 
+// Disconnect removes any files the credentials were written to.
 func (c *WorkspaceExternal) Disconnect(ctx context.Context) error {
-	// Nothing to disconnect for this implementation
+	c.creds.close()
 	return nil
 }
 
+// setup initialises the working directory of the supplied Workspace resource
+// against its backend, and returns a Terraform executor for it.
+func (c *WorkspaceExternal) setup(ctx context.Context, cr *v1alpha1.Workspace) (*tfexec.Terraform, error) {
+	if err := writeBackendConfig(c.service.workDir, cr.Spec.ForProvider.Backend); err != nil {
+		return nil, errors.Wrap(err, errWriteBackend)
+	}
+	tf, err := tfexec.NewTerraform(c.service.workDir, "terraform")
+	if err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+	// Every run reaches the backend, so needs the credentials.
+	if err := c.creds.load(ctx); err != nil {
+		return nil, errors.Wrap(err, errGetCreds)
+	}
+	env := map[string]string{}
+	cr.Status.AtProvider.CredentialLeases = nil
+	if c.creds != nil {
+		maps.Copy(env, c.creds.env)
+		cr.Status.AtProvider.CredentialLeases = c.creds.leases
+	}
+	maps.Copy(env, cr.Spec.ForProvider.Environment)
+	if len(env) > 0 {
+		if err := tf.SetEnv(withEnviron(env)); err != nil {
+			return nil, errors.Wrap(err, errSetEnvironment)
+		}
+	}
+	if err := tf.Init(ctx, tfexec.Reconfigure(true)); err != nil {
+		return nil, errors.Wrap(err, errInitTF)
+	}
+	return tf, nil
+}
+
+// destroyWorkspace destroys the resources the state of the named workspace
+// manages. The working directory has no configuration, so they are
+// destroyed with the providers their state requires, configured by the
+// environment alone.
+func destroyWorkspace(ctx context.Context, tf *tfexec.Terraform, name string) error {
+	if err := tf.WorkspaceSelect(ctx, name); err != nil {
+		return errors.Wrap(err, errDestroyWorkspace)
+	}
+	// Install the providers the workspace's state requires.
+	if err := tf.Init(ctx); err != nil {
+		return errors.Wrap(err, errDestroyWorkspace)
+	}
+	return errors.Wrap(tf.Destroy(ctx), errDestroyWorkspace)
+}
+
+// withEnviron returns the environment of the provider, overridden by the
+// supplied variables. Terraform is otherwise run with only the supplied
+// variables.
+func withEnviron(env map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			out[k] = v
+		}
+	}
+	for k, v := range env {
+		out[k] = v
+	}
+	return out
+}
+
+// workspaceExists returns true if the named workspace exists.
+func workspaceExists(ctx context.Context, tf *tfexec.Terraform, name string) (bool, error) {
+	workspaces, _, err := tf.WorkspaceList(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, errListWorkspaces)
+	}
+	for _, ws := range workspaces {
+		if ws == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// workspaceState is the part of a Terraform state file describing how much it
+// manages. Reading the raw state, rather than having Terraform show it, works
+// without the providers that manage its resources.
+type workspaceState struct {
+	Serial    int64 `json:"serial"`
+	Resources []struct {
+		Mode      string            `json:"mode"`
+		Instances []json.RawMessage `json:"instances"`
+	} `json:"resources"`
+}
+
+// pullWorkspaceState reads the state of the selected workspace. A workspace
+// Terraform has never run in has no state.
+func pullWorkspaceState(ctx context.Context, tf *tfexec.Terraform) (*workspaceState, error) {
+	raw, err := tf.StatePull(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadWorkspaceState)
+	}
+	s := &workspaceState{}
+	if strings.TrimSpace(raw) == "" {
+		return s, nil
+	}
+	return s, errors.Wrap(json.Unmarshal([]byte(raw), s), errReadWorkspaceState)
+}
+
+// managedResources returns the number of resource instances the state
+// manages. Data sources are read, not managed.
+func (s *workspaceState) managedResources() int {
+	n := 0
+	for _, r := range s.Resources {
+		if r.Mode == "managed" {
+			n += len(r.Instances)
+		}
+	}
+	return n
+}
+
+// observeWorkspace records the supplied state of a workspace in the status of
+// the supplied Workspace resource.
+func observeWorkspace(cr *v1alpha1.Workspace, s *workspaceState) {
+	now := metav1.NewTime(time.Now())
+	o := &cr.Status.AtProvider
+	o.ID = cr.Spec.ForProvider.Name
+	o.Status = WorkspaceAvailable
+	o.ResourceCount = s.managedResources()
+	if o.CreatedAt == nil {
+		o.CreatedAt = &now
+	}
+	if o.UpdatedAt == nil || o.StateSerial != s.Serial {
+		o.UpdatedAt = &now
+	}
+	o.StateSerial = s.Serial
+}
+
 // SetupWorkspace adds a controller that reconciles Workspace managed resources.
 func SetupWorkspace(mgr ctrl.Manager, o controller.Options) error {
 	name := managed.ControllerName(v1alpha1.WorkspaceGroupKind.Kind)
@@ -121,7 +360,10 @@ func SetupWorkspace(mgr ctrl.Manager, o controller.Options) error {
 
 	r := managed.NewReconciler(mgr,
 		resource.ManagedKind(v1alpha1.WorkspaceGroupVersionKind),
-		managed.WithExternalConnecter(&WorkspaceConnector{}),
+		managed.WithExternalConnecter(&WorkspaceConnector{
+			kube:  mgr.GetClient(),
+			usage: resource.NewProviderConfigUsageTracker(mgr.GetClient(), &v1alpha1.ProviderConfigUsage{}),
+		}),
 		managed.WithLogger(o.Logger.WithValues("controller", name)),
 		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
 		managed.WithConnectionPublishers(cps...))
@@ -0,0 +1,282 @@
+package tfconfig
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+var rootSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "terraform"},
+	},
+}
+
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "type"},
+		{Name: "default"},
+		{Name: "description"},
+		{Name: "sensitive"},
+	},
+}
+
+var outputSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "description"},
+		{Name: "sensitive"},
+	},
+}
+
+var terraformSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "required_providers"},
+	},
+}
+
+// Parse the supplied Terraform configuration file. Files ending in .json are
+// parsed as JSON configuration syntax, anything else as HCL.
+func Parse(name string, content []byte) (*hcl.File, hcl.Diagnostics) {
+	if strings.HasSuffix(name, ".json") {
+		return hcljson.Parse(content, name)
+	}
+	return hclsyntax.ParseConfig(content, name, hcl.InitialPos)
+}
+
+// IsConfigFile returns true if Terraform reads the named file as
+// configuration.
+func IsConfigFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")
+}
+
+// A Module is the interface of a Terraform module, as declared by its
+// configuration.
+type Module struct {
+	Interface *v1alpha1.ModuleInterface
+
+	types map[string]cty.Type
+}
+
+// RootModule returns the module made up of the supplied files, keyed by
+// their path relative to the working directory, that are in the supplied
+// directory, relative to the working directory, that Terraform runs in.
+// Files in other directories belong to other modules and are ignored.
+func RootModule(files map[string][]byte, dir string) (*Module, hcl.Diagnostics) {
+	m := &Module{Interface: &v1alpha1.ModuleInterface{}, types: map[string]cty.Type{}}
+
+	dir = path.Clean(dir)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if path.Dir(name) == dir && IsConfigFile(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diags hcl.Diagnostics
+	for _, name := range names {
+		f, d := Parse(name, files[name])
+		diags = append(diags, d...)
+		if d.HasErrors() {
+			continue
+		}
+		diags = append(diags, m.decode(f.Body)...)
+	}
+
+	sort.Slice(m.Interface.Variables, func(i, j int) bool { return m.Interface.Variables[i].Name < m.Interface.Variables[j].Name })
+	sort.Slice(m.Interface.Outputs, func(i, j int) bool { return m.Interface.Outputs[i].Name < m.Interface.Outputs[j].Name })
+	sort.Slice(m.Interface.RequiredProviders, func(i, j int) bool {
+		return m.Interface.RequiredProviders[i].Name < m.Interface.RequiredProviders[j].Name
+	})
+	return m, diags
+}
+
+func (m *Module) decode(body hcl.Body) hcl.Diagnostics {
+	content, _, diags := body.PartialContent(rootSchema)
+	for _, b := range content.Blocks {
+		switch b.Type {
+		case "variable":
+			diags = append(diags, m.decodeVariable(b)...)
+		case "output":
+			diags = append(diags, m.decodeOutput(b)...)
+		case "terraform":
+			diags = append(diags, m.decodeTerraform(b)...)
+		}
+	}
+	return diags
+}
+
+func (m *Module) decodeVariable(b *hcl.Block) hcl.Diagnostics {
+	content, _, diags := b.Body.PartialContent(variableSchema)
+	v := v1alpha1.ModuleVariable{Name: b.Labels[0], Required: true}
+
+	ty := cty.DynamicPseudoType
+	if attr, ok := content.Attributes["type"]; ok {
+		t, _, d := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+		diags = append(diags, d...)
+		if !d.HasErrors() {
+			ty = t
+			v.Type = typeexpr.TypeString(t)
+		}
+	}
+	if _, ok := content.Attributes["default"]; ok {
+		v.Required = false
+	}
+	v.Description = stringAttr(content.Attributes["description"])
+	v.Sensitive = boolAttr(content.Attributes["sensitive"])
+
+	m.types[v.Name] = ty
+	m.Interface.Variables = append(m.Interface.Variables, v)
+	return diags
+}
+
+func (m *Module) decodeOutput(b *hcl.Block) hcl.Diagnostics {
+	content, _, diags := b.Body.PartialContent(outputSchema)
+	m.Interface.Outputs = append(m.Interface.Outputs, v1alpha1.ModuleOutput{
+		Name:        b.Labels[0],
+		Description: stringAttr(content.Attributes["description"]),
+		Sensitive:   boolAttr(content.Attributes["sensitive"]),
+	})
+	return diags
+}
+
+func (m *Module) decodeTerraform(b *hcl.Block) hcl.Diagnostics {
+	content, _, diags := b.Body.PartialContent(terraformSchema)
+	for _, rp := range content.Blocks {
+		attrs, d := rp.Body.JustAttributes()
+		diags = append(diags, d...)
+		for name, attr := range attrs {
+			p := v1alpha1.RequiredProvider{Name: name}
+			val, d := attr.Expr.Value(nil)
+			if d.HasErrors() {
+				continue
+			}
+			switch {
+			case val.Type() == cty.String && val.IsKnown() && !val.IsNull():
+				// The legacy form, which only gives a version constraint.
+				p.Version = val.AsString()
+			case val.Type().IsObjectType() && val.IsKnown() && !val.IsNull():
+				if val.Type().HasAttribute("source") {
+					p.Source = ctyString(val.GetAttr("source"))
+				}
+				if val.Type().HasAttribute("version") {
+					p.Version = ctyString(val.GetAttr("version"))
+				}
+			}
+			m.Interface.RequiredProviders = append(m.Interface.RequiredProviders, p)
+		}
+	}
+	return diags
+}
+
+// CheckVariables returns a description of each problem with the supplied
+// variables: variables the module requires that are not supplied, and
+// supplied values that cannot be converted to the declared type. Variables
+// the module does not declare are not a problem; Terraform ignores them, so
+// they are returned by Undeclared instead.
+func (m *Module) CheckVariables(vars map[string]string) []string {
+	var problems []string
+	for _, v := range m.Interface.Variables {
+		if _, ok := vars[v.Name]; !ok && v.Required {
+			problems = append(problems, fmt.Sprintf("required variable %q is not set", v.Name))
+		}
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ty, ok := m.types[name]
+		if !ok {
+			continue
+		}
+		// Variables are supplied as strings, which Terraform converts to
+		// the declared type.
+		if _, err := convert.Convert(cty.StringVal(vars[name]), ty); err != nil {
+			problems = append(problems, fmt.Sprintf("variable %q cannot be converted to %s: %s", name, typeexpr.TypeString(ty), err))
+		}
+	}
+	return problems
+}
+
+// Undeclared returns the sorted names of the supplied variables that the
+// module does not declare.
+func (m *Module) Undeclared(vars map[string]string) []string {
+	var names []string
+	for name := range vars {
+		if !m.Declares(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Declares returns true if the module declares the named variable.
+func (m *Module) Declares(name string) bool {
+	_, ok := m.types[name]
+	return ok
+}
+
+// Sensitive returns true if the module declares the named variable as
+// sensitive.
+func (m *Module) Sensitive(name string) bool {
+	for _, v := range m.Interface.Variables {
+		if v.Name == name {
+			return v.Sensitive
+		}
+	}
+	return false
+}
+
+func stringAttr(attr *hcl.Attribute) string {
+	if attr == nil {
+		return ""
+	}
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return ""
+	}
+	return ctyString(v)
+}
+
+func boolAttr(attr *hcl.Attribute) bool {
+	if attr == nil {
+		return false
+	}
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || v.IsNull() || !v.IsKnown() {
+		return false
+	}
+	v, err := convert.Convert(v, cty.Bool)
+	if err != nil {
+		return false
+	}
+	return v.True()
+}
+
+func ctyString(v cty.Value) string {
+	if v.IsNull() || !v.IsKnown() {
+		return ""
+	}
+	v, err := convert.Convert(v, cty.String)
+	if err != nil {
+		return ""
+	}
+	return v.AsString()
+}
@@ -0,0 +1,177 @@
+package tfconfig
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	moduleHCL = `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    null = "~> 3.0"
+  }
+}
+
+variable "region" {
+  type        = string
+  description = "Region to deploy to."
+}
+
+variable "replicas" {
+  type    = number
+  default = 1
+}
+
+variable "password" {
+  sensitive = true
+}
+
+output "arn" {
+  description = "ARN of the bucket."
+  sensitive   = true
+}
+`
+	moduleJSON = `{"variable": {"tags": {"type": "map(string)", "default": {}}}, "output": {"id": {"value": "x"}}}`
+)
+
+func TestRootModule(t *testing.T) {
+	type want struct {
+		iface *v1alpha1.ModuleInterface
+		err   bool
+	}
+	cases := map[string]struct {
+		reason string
+		files  map[string][]byte
+		dir    string
+		want   want
+	}{
+		"Interface": {
+			reason: "The variables, outputs and required providers declared by HCL and JSON files should be published in order.",
+			files:  map[string][]byte{"main.tf": []byte(moduleHCL), "extra.tf.json": []byte(moduleJSON)},
+			dir:    ".",
+			want: want{iface: &v1alpha1.ModuleInterface{
+				Variables: []v1alpha1.ModuleVariable{
+					{Name: "password", Required: true, Sensitive: true},
+					{Name: "region", Type: "string", Description: "Region to deploy to.", Required: true},
+					{Name: "replicas", Type: "number"},
+					{Name: "tags", Type: "map(string)"},
+				},
+				Outputs: []v1alpha1.ModuleOutput{
+					{Name: "arn", Description: "ARN of the bucket.", Sensitive: true},
+					{Name: "id"},
+				},
+				RequiredProviders: []v1alpha1.RequiredProvider{
+					{Name: "aws", Source: "hashicorp/aws", Version: "~> 5.0"},
+					{Name: "null", Version: "~> 3.0"},
+				},
+			}},
+		},
+		"OtherDirectories": {
+			reason: "Files outside the directory Terraform runs in, and files Terraform doesn't read, should be ignored.",
+			files: map[string][]byte{
+				"envs/prod/main.tf":   []byte(`variable "env" {}`),
+				"modules/vpc/main.tf": []byte(`variable "cidr" {}`),
+				"envs/prod/README.md": []byte(`variable "ignored" {}`),
+			},
+			dir: "envs/prod/",
+			want: want{iface: &v1alpha1.ModuleInterface{
+				Variables: []v1alpha1.ModuleVariable{{Name: "env", Required: true}},
+			}},
+		},
+		"Invalid": {
+			reason: "A file that can't be parsed should be reported.",
+			files:  map[string][]byte{"main.tf": []byte(`variable "region" {`)},
+			dir:    ".",
+			want:   want{iface: &v1alpha1.ModuleInterface{}, err: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			m, diags := RootModule(tc.files, tc.dir)
+			if diags.HasErrors() != tc.want.err {
+				t.Errorf("\n%s\nRootModule(...): want error %t, got %v", tc.reason, tc.want.err, diags)
+			}
+			if diff := cmp.Diff(tc.want.iface, m.Interface); diff != "" {
+				t.Errorf("\n%s\nRootModule(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckVariables(t *testing.T) {
+	m, diags := RootModule(map[string][]byte{"main.tf": []byte(moduleHCL)}, ".")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	cases := map[string]struct {
+		reason string
+		vars   map[string]string
+		want   []string
+	}{
+		"Valid": {
+			reason: "Variables that are all set and convertible should have no problems.",
+			vars:   map[string]string{"region": "us-east-1", "replicas": "3", "password": "secret"},
+		},
+		"Undeclared": {
+			reason: "Undeclared variables should not be a problem.",
+			vars:   map[string]string{"region": "us-east-1", "password": "secret", "unused": "x"},
+		},
+		"Problems": {
+			reason: "Missing required variables and values of the wrong type should be problems.",
+			vars:   map[string]string{"replicas": "three"},
+			want: []string{
+				`required variable "password" is not set`,
+				`required variable "region" is not set`,
+				`variable "replicas" cannot be converted to number: a number is required`,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := m.CheckVariables(tc.vars)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nCheckVariables(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUndeclared(t *testing.T) {
+	m, diags := RootModule(map[string][]byte{"main.tf": []byte(moduleHCL)}, ".")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	cases := map[string]struct {
+		reason string
+		vars   map[string]string
+		want   []string
+	}{
+		"Declared": {
+			reason: "No variable should be undeclared if the module declares them all.",
+			vars:   map[string]string{"region": "us-east-1"},
+		},
+		"Undeclared": {
+			reason: "Variables the module doesn't declare should be returned in order.",
+			vars:   map[string]string{"region": "us-east-1", "zone": "a", "env": "prod"},
+			want:   []string{"env", "zone"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := m.Undeclared(tc.vars)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nUndeclared(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package tfconfig renders the configuration of a Terraform resource into
+// the files Terraform reads.
+package tfconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errEmpty     = "configuration is empty"
+	errDecodeHCL = "cannot decode HCL configuration"
+	errKind      = "configuration must be a string of HCL or a JSON object"
+
+	errPathEmpty        = "path is empty"
+	errPathBackslash    = "path must use forward slashes"
+	errPathAbsolute     = "path must be relative to the working directory"
+	errPathNotClean     = "path must be clean, without empty, . or .. elements"
+	errPathEscapes      = "path must not escape the working directory"
+	errPathReserved     = "%s is managed by the provider"
+	errPathTerraformDir = "path must not be within Terraform's .terraform directory"
+)
+
+// Names of the files a configuration is written to. Terraform reads files
+// ending in .tf.json as JSON configuration syntax.
+const (
+	MainFile     = "main.tf"
+	MainJSONFile = "main.tf.json"
+
+	// ManifestFile records the paths of the files written from a
+	// configuration, so that files removed from it can be removed from
+	// disk.
+	ManifestFile = ".crossplane-files"
+)
+
+// Main returns the name and contents of the file the supplied raw
+// configuration is written to. A JSON string holds HCL, which is written to
+// main.tf. A JSON object holds Terraform's JSON configuration syntax, which
+// is written as is to main.tf.json.
+func Main(raw []byte) (string, []byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "", nil, errors.New(errEmpty)
+	}
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", nil, errors.Wrap(err, errDecodeHCL)
+		}
+		return MainFile, []byte(s), nil
+	case '{':
+		return MainJSONFile, raw, nil
+	default:
+		return "", nil, errors.New(errKind)
+	}
+}
+
+// ReservedFiles are written or read by the provider itself, and cannot be
+// supplied as part of a configuration.
+var ReservedFiles = map[string]bool{
+	MainFile:                   true,
+	MainJSONFile:               true,
+	"backend.tf":               true,
+	"imports.tf":               true,
+	"crossplane_providers.tf":  true,
+	"terraform.tfvars":         true,
+	"terraform.tfstate":        true,
+	"terraform.tfstate.backup": true,
+	"errored.tfstate":          true,
+	"migrated.tfstate":         true,
+	"tfplan":                   true,
+	ManifestFile:               true,
+}
+
+// ValidatePath returns an error if the supplied path of a configuration file
+// is not a clean path relative to, and within, the working directory, or is
+// a file the provider manages itself.
+func ValidatePath(p string) error {
+	switch {
+	case p == "":
+		return errors.New(errPathEmpty)
+	case strings.Contains(p, `\`):
+		return errors.New(errPathBackslash)
+	case path.IsAbs(p):
+		return errors.New(errPathAbsolute)
+	case path.Clean(p) != p:
+		return errors.New(errPathNotClean)
+	case p == "." || p == ".." || strings.HasPrefix(p, "../"):
+		return errors.New(errPathEscapes)
+	case ReservedFiles[p]:
+		return errors.Errorf(errPathReserved, p)
+	}
+	for _, c := range strings.Split(p, "/") {
+		if strings.HasPrefix(c, ".terraform") {
+			return errors.New(errPathTerraformDir)
+		}
+	}
+	return nil
+}
+
+// ValidatePathIn returns an error if the supplied path of a configuration
+// file is invalid, or is a file the provider manages itself in the supplied
+// directory, relative to the working directory, that Terraform runs in.
+func ValidatePathIn(p, dir string) error {
+	if err := ValidatePath(p); err != nil {
+		return err
+	}
+	if path.Dir(p) == path.Clean(dir) && ReservedFiles[path.Base(p)] {
+		return errors.Errorf(errPathReserved, p)
+	}
+	return nil
+}
+
+// Files returns the contents of every configuration file of the supplied
+// parameters, keyed by their path relative to the working directory. The
+// configuration itself is written to the supplied directory, relative to the
+// working directory, that Terraform runs in.
+func Files(p *v1alpha1.TerraformParameters, dir string) (map[string][]byte, error) {
+	name, content, err := Main(p.Configuration.Raw)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(p.Files)+1)
+	files[path.Join(dir, name)] = content
+	for k, v := range p.Files {
+		files[k] = []byte(v)
+	}
+	return files, nil
+}
@@ -0,0 +1,117 @@
+package tfconfig
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMainFile(t *testing.T) {
+	type want struct {
+		name    string
+		content string
+		err     bool
+	}
+	cases := map[string]struct {
+		reason string
+		raw    string
+		want   want
+	}{
+		"HCL": {
+			reason: "A JSON string should be written to main.tf as HCL.",
+			raw:    `"resource \"null_resource\" \"this\" {}\n"`,
+			want:   want{name: MainFile, content: "resource \"null_resource\" \"this\" {}\n"},
+		},
+		"JSON": {
+			reason: "A JSON object should be written as is to main.tf.json.",
+			raw:    ` {"resource": {"null_resource": {"this": {}}}}` + "\n",
+			want:   want{name: MainJSONFile, content: `{"resource": {"null_resource": {"this": {}}}}`},
+		},
+		"Empty": {
+			reason: "An empty configuration should be rejected.",
+			raw:    "  ",
+			want:   want{err: true},
+		},
+		"InvalidString": {
+			reason: "A JSON string that can't be decoded should be rejected.",
+			raw:    `"unterminated`,
+			want:   want{err: true},
+		},
+		"Array": {
+			reason: "A configuration that is neither a string nor an object should be rejected.",
+			raw:    `["resource"]`,
+			want:   want{err: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			n, content, err := Main([]byte(tc.raw))
+			got := want{name: n, content: string(content), err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nMain(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		path   string
+		want   bool
+	}{
+		"File":          {reason: "A file in the working directory should be valid.", path: "variables.tf", want: true},
+		"Module":        {reason: "A file of a local module should be valid.", path: "modules/vpc/main.tf", want: true},
+		"Empty":         {reason: "An empty path should be invalid.", path: ""},
+		"Backslash":     {reason: "A path with backslashes should be invalid.", path: `modules\vpc\main.tf`},
+		"Absolute":      {reason: "An absolute path should be invalid.", path: "/etc/passwd"},
+		"NotClean":      {reason: "A path that isn't clean should be invalid.", path: "modules/./vpc/main.tf"},
+		"Escapes":       {reason: "A path outside the working directory should be invalid.", path: "../main.tf"},
+		"Dot":           {reason: "The working directory itself should be invalid.", path: "."},
+		"Reserved":      {reason: "A file the provider manages should be invalid.", path: "backend.tf"},
+		"TerraformDir":  {reason: "A file in Terraform's .terraform directory should be invalid.", path: ".terraform/modules/modules.json"},
+		"NestedReserve": {reason: "A reserved name in another directory should be valid.", path: "modules/vpc/main.tf.json", want: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidatePath(tc.path)
+			if got := err == nil; got != tc.want {
+				t.Errorf("\n%s\nValidatePath(%q): want valid %t, got %v", tc.reason, tc.path, tc.want, err)
+			}
+		})
+	}
+}
+
+func TestValidatePathIn(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		path   string
+		dir    string
+		want   bool
+	}{
+		"OtherDirectory": {
+			reason: "A reserved name outside the directory Terraform runs in should be valid.",
+			path:   "modules/vpc/main.tf",
+			dir:    "envs/prod",
+			want:   true,
+		},
+		"RunDirectory": {
+			reason: "A reserved name in the directory Terraform runs in should be invalid.",
+			path:   "envs/prod/backend.tf",
+			dir:    "envs/prod/",
+		},
+		"Invalid": {
+			reason: "An invalid path should be invalid in any directory.",
+			path:   "../main.tf",
+			dir:    ".",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidatePathIn(tc.path, tc.dir)
+			if got := err == nil; got != tc.want {
+				t.Errorf("\n%s\nValidatePathIn(%q, %q): want valid %t, got %v", tc.reason, tc.path, tc.dir, tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+// Package tfe is a client for the parts of the Terraform Cloud and Terraform
+// Enterprise API used to manage workspaces.
+package tfe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultAddress is the address of Terraform Cloud.
+	DefaultAddress = "https://app.terraform.io"
+
+	mediaType = "application/vnd.api+json"
+	apiPath   = "/api/v2"
+
+	errRequest  = "cannot %s %s"
+	errDecode   = "cannot decode response to %s %s"
+	errStatus   = "%s %s returned %d: %s"
+	errAddress  = "invalid API address %q"
+	errNotFound = "not found"
+)
+
+// Variable categories.
+const (
+	CategoryTerraform = "terraform"
+	CategoryEnv       = "env"
+)
+
+// RunErrored is the status of a run that failed.
+const RunErrored = "errored"
+
+// Run statuses that are final; a run in any other status may still change.
+var finalRunStatuses = map[string]bool{
+	"applied":              true,
+	"planned_and_finished": true,
+	RunErrored:             true,
+	"canceled":             true,
+	"force_canceled":       true,
+	"discarded":            true,
+	"policy_soft_failed":   true,
+}
+
+// IsFinal returns true if a run with the supplied status will not change
+// again.
+func IsFinal(status string) bool {
+	return finalRunStatuses[status]
+}
+
+// A Client of the Terraform Cloud or Terraform Enterprise API.
+type Client struct {
+	address string
+	token   string
+	http    *http.Client
+}
+
+// NewClient returns a client of the API at the supplied address, e.g.
+// https://app.terraform.io, authenticating with the supplied token.
+func NewClient(address, token string) (*Client, error) {
+	if address == "" {
+		address = DefaultAddress
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf(errAddress, address)
+	}
+	return &Client{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// A Workspace in Terraform Cloud or Terraform Enterprise.
+type Workspace struct {
+	ID               string
+	Name             string
+	AutoApply        bool
+	TerraformVersion string
+	WorkingDirectory string
+	Locked           bool
+	ResourceCount    int
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	CurrentRunID     string
+}
+
+// WorkspaceOptions are the settings of a workspace managed by the client.
+type WorkspaceOptions struct {
+	Name             string `json:"name,omitempty"`
+	AutoApply        bool   `json:"auto-apply"`
+	TerraformVersion string `json:"terraform-version,omitempty"`
+	WorkingDirectory string `json:"working-directory"`
+}
+
+// A Variable of a workspace.
+type Variable struct {
+	ID          string `json:"-"`
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Sensitive   bool   `json:"sensitive"`
+}
+
+// A Run of a workspace.
+type Run struct {
+	ID        string
+	Status    string
+	Message   string
+	IsDestroy bool
+	CreatedAt time.Time
+}
+
+// RunOptions configure a run to be queued.
+type RunOptions struct {
+	Message   string `json:"message,omitempty"`
+	IsDestroy bool   `json:"is-destroy"`
+	AutoApply *bool  `json:"auto-apply,omitempty"`
+}
+
+type resource struct {
+	ID            string                  `json:"id,omitempty"`
+	Type          string                  `json:"type"`
+	Attributes    json.RawMessage         `json:"attributes,omitempty"`
+	Relationships map[string]relationship `json:"relationships,omitempty"`
+}
+
+type relationship struct {
+	Data *resourceID `json:"data"`
+}
+
+type resourceID struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type document struct {
+	Data json.RawMessage `json:"data"`
+}
+
+type workspaceAttributes struct {
+	Name             string    `json:"name"`
+	AutoApply        bool      `json:"auto-apply"`
+	TerraformVersion string    `json:"terraform-version"`
+	WorkingDirectory string    `json:"working-directory"`
+	Locked           bool      `json:"locked"`
+	ResourceCount    int       `json:"resource-count"`
+	CreatedAt        time.Time `json:"created-at"`
+	UpdatedAt        time.Time `json:"updated-at"`
+}
+
+type runAttributes struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	IsDestroy bool      `json:"is-destroy"`
+	CreatedAt time.Time `json:"created-at"`
+}
+
+// ReadWorkspace returns the named workspace of the supplied organization.
+func (c *Client) ReadWorkspace(ctx context.Context, org, name string) (*Workspace, error) {
+	r := &resource{}
+	if err := c.do(ctx, http.MethodGet, "/organizations/"+url.PathEscape(org)+"/workspaces/"+url.PathEscape(name), nil, r); err != nil {
+		return nil, err
+	}
+	return workspaceFrom(r)
+}
+
+// CreateWorkspace creates a workspace in the supplied organization.
+func (c *Client) CreateWorkspace(ctx context.Context, org string, o WorkspaceOptions) (*Workspace, error) {
+	r := &resource{}
+	if err := c.do(ctx, http.MethodPost, "/organizations/"+url.PathEscape(org)+"/workspaces", newResource("", "workspaces", o), r); err != nil {
+		return nil, err
+	}
+	return workspaceFrom(r)
+}
+
+// UpdateWorkspace updates the settings of the identified workspace.
+func (c *Client) UpdateWorkspace(ctx context.Context, id string, o WorkspaceOptions) (*Workspace, error) {
+	r := &resource{}
+	if err := c.do(ctx, http.MethodPatch, "/workspaces/"+url.PathEscape(id), newResource(id, "workspaces", o), r); err != nil {
+		return nil, err
+	}
+	return workspaceFrom(r)
+}
+
+// SafeDeleteWorkspace deletes the identified workspace. The API refuses to
+// delete a workspace whose state still manages resources.
+func (c *Client) SafeDeleteWorkspace(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/workspaces/"+url.PathEscape(id)+"/actions/safe-delete", nil, nil)
+}
+
+// ListVariables returns the variables of the identified workspace.
+func (c *Client) ListVariables(ctx context.Context, workspaceID string) ([]Variable, error) {
+	var rs []resource
+	if err := c.do(ctx, http.MethodGet, "/workspaces/"+url.PathEscape(workspaceID)+"/vars", nil, &rs); err != nil {
+		return nil, err
+	}
+	vars := make([]Variable, 0, len(rs))
+	for _, r := range rs {
+		v := Variable{ID: r.ID}
+		if err := json.Unmarshal(r.Attributes, &v); err != nil {
+			return nil, err
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+// CreateVariable creates a variable of the identified workspace.
+func (c *Client) CreateVariable(ctx context.Context, workspaceID string, v Variable) error {
+	return c.do(ctx, http.MethodPost, "/workspaces/"+url.PathEscape(workspaceID)+"/vars", newResource("", "vars", v), nil)
+}
+
+// UpdateVariable updates a variable of the identified workspace.
+func (c *Client) UpdateVariable(ctx context.Context, workspaceID string, v Variable) error {
+	return c.do(ctx, http.MethodPatch, "/workspaces/"+url.PathEscape(workspaceID)+"/vars/"+url.PathEscape(v.ID), newResource(v.ID, "vars", v), nil)
+}
+
+// DeleteVariable deletes a variable of the identified workspace.
+func (c *Client) DeleteVariable(ctx context.Context, workspaceID, id string) error {
+	return c.do(ctx, http.MethodDelete, "/workspaces/"+url.PathEscape(workspaceID)+"/vars/"+url.PathEscape(id), nil, nil)
+}
+
+// CreateRun queues a run of the identified workspace.
+func (c *Client) CreateRun(ctx context.Context, workspaceID string, o RunOptions) (*Run, error) {
+	req := newResource("", "runs", o)
+	req.Relationships = map[string]relationship{
+		"workspace": {Data: &resourceID{ID: workspaceID, Type: "workspaces"}},
+	}
+	r := &resource{}
+	if err := c.do(ctx, http.MethodPost, "/runs", req, r); err != nil {
+		return nil, err
+	}
+	return runFrom(r)
+}
+
+// ReadRun returns the identified run.
+func (c *Client) ReadRun(ctx context.Context, id string) (*Run, error) {
+	r := &resource{}
+	if err := c.do(ctx, http.MethodGet, "/runs/"+url.PathEscape(id), nil, r); err != nil {
+		return nil, err
+	}
+	return runFrom(r)
+}
+
+func newResource(id, typ string, attrs any) *resource {
+	b, _ := json.Marshal(attrs) //nolint:errchkjson // Attributes are always plain structs.
+	return &resource{ID: id, Type: typ, Attributes: b}
+}
+
+func workspaceFrom(r *resource) (*Workspace, error) {
+	a := workspaceAttributes{}
+	if err := json.Unmarshal(r.Attributes, &a); err != nil {
+		return nil, err
+	}
+	ws := &Workspace{
+		ID:               r.ID,
+		Name:             a.Name,
+		AutoApply:        a.AutoApply,
+		TerraformVersion: a.TerraformVersion,
+		WorkingDirectory: a.WorkingDirectory,
+		Locked:           a.Locked,
+		ResourceCount:    a.ResourceCount,
+		CreatedAt:        a.CreatedAt,
+		UpdatedAt:        a.UpdatedAt,
+	}
+	if cr, ok := r.Relationships["current-run"]; ok && cr.Data != nil {
+		ws.CurrentRunID = cr.Data.ID
+	}
+	return ws, nil
+}
+
+func runFrom(r *resource) (*Run, error) {
+	a := runAttributes{}
+	if err := json.Unmarshal(r.Attributes, &a); err != nil {
+		return nil, err
+	}
+	return &Run{ID: r.ID, Status: a.Status, Message: a.Message, IsDestroy: a.IsDestroy, CreatedAt: a.CreatedAt}, nil
+}
+
+// do sends a request with the supplied resource as its data to the supplied
+// path, and decodes the data of the response into out, if non-nil.
+func (c *Client) do(ctx context.Context, method, path string, in *resource, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(map[string]any{"data": in})
+		if err != nil {
+			return errors.Wrapf(err, errRequest, method, path)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+apiPath+path, body)
+	if err != nil {
+		return errors.Wrapf(err, errRequest, method, path)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", mediaType)
+	if in != nil {
+		req.Header.Set("Content-Type", mediaType)
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, errRequest, method, path)
+	}
+	defer rsp.Body.Close() //nolint:errcheck // Nothing to do about it.
+
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return errors.Wrapf(err, errRequest, method, path)
+	}
+	if rsp.StatusCode == http.StatusNotFound {
+		return &notFoundError{msg: fmt.Sprintf(errStatus, method, path, rsp.StatusCode, errNotFound)}
+	}
+	if rsp.StatusCode >= 300 {
+		return errors.Errorf(errStatus, method, path, rsp.StatusCode, apiErrors(b))
+	}
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+
+	d := document{}
+	if err := json.Unmarshal(b, &d); err != nil {
+		return errors.Wrapf(err, errDecode, method, path)
+	}
+	return errors.Wrapf(json.Unmarshal(d.Data, out), errDecode, method, path)
+}
+
+// apiErrors returns the errors described by the supplied JSON:API error
+// document, or the document itself if it isn't one.
+func apiErrors(b []byte) string {
+	d := struct {
+		Errors []struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}{}
+	if err := json.Unmarshal(b, &d); err != nil || len(d.Errors) == 0 {
+		return strings.TrimSpace(string(b))
+	}
+	msgs := make([]string, 0, len(d.Errors))
+	for _, e := range d.Errors {
+		msg := e.Title
+		if e.Detail != "" {
+			msg += ": " + e.Detail
+		}
+		msgs = append(msgs, msg)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type notFoundError struct {
+	msg string
+}
+
+func (e *notFoundError) Error() string {
+	return e.msg
+}
+
+// IsNotFound returns true if the supplied error was returned because what
+// was requested does not exist. The API also answers requests the token is
+// not authorized for as if what was requested does not exist.
+func IsNotFound(err error) bool {
+	_, ok := errors.Cause(err).(*notFoundError) //nolint:errorlint // pkg/errors doesn't support As.
+	return ok
+}
@@ -0,0 +1,452 @@
+package tfe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const (
+	testToken = "secret-token"
+	testOrg   = "example"
+)
+
+// A fakeAPI is an in-memory stand-in for the parts of the API the client
+// uses.
+type fakeAPI struct {
+	mu         sync.Mutex
+	next       int
+	workspaces map[string]*resource
+	vars       map[string]map[string]*resource
+	runs       map[string]*resource
+}
+
+func newFakeAPI(t *testing.T) (*Client, *fakeAPI) {
+	t.Helper()
+	f := &fakeAPI{
+		workspaces: map[string]*resource{},
+		vars:       map[string]map[string]*resource{},
+		runs:       map[string]*resource{},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL, testToken)
+	if err != nil {
+		t.Fatalf("NewClient(...): %v", err)
+	}
+	return c, f
+}
+
+func (f *fakeAPI) id(prefix string) string {
+	f.next++
+	return fmt.Sprintf("%s-%d", prefix, f.next)
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		writeErrors(w, http.StatusUnauthorized, "unauthorized", "")
+		return
+	}
+	if r.Header.Get("Accept") != mediaType {
+		writeErrors(w, http.StatusNotAcceptable, "not acceptable", "")
+		return
+	}
+	in := &resource{}
+	if r.Body != nil && r.ContentLength != 0 {
+		d := struct {
+			Data *resource `json:"data"`
+		}{Data: in}
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil || r.Header.Get("Content-Type") != mediaType {
+			writeErrors(w, http.StatusBadRequest, "bad request", "")
+			return
+		}
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, apiPath+"/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "organizations" && parts[2] == "workspaces" && r.Method == http.MethodPost:
+		a := workspaceAttributes{}
+		_ = json.Unmarshal(in.Attributes, &a)
+		for _, ws := range f.workspaces {
+			if workspaceName(ws) == a.Name {
+				writeErrors(w, http.StatusUnprocessableEntity, "invalid attribute", "Name has already been taken")
+				return
+			}
+		}
+		ws := &resource{ID: f.id("ws"), Type: "workspaces", Attributes: mustJSON(a)}
+		f.workspaces[ws.ID] = ws
+		writeData(w, http.StatusCreated, ws)
+	case len(parts) == 4 && parts[0] == "organizations" && parts[2] == "workspaces" && r.Method == http.MethodGet:
+		for _, ws := range f.workspaces {
+			if workspaceName(ws) == parts[3] {
+				writeData(w, http.StatusOK, ws)
+				return
+			}
+		}
+		writeErrors(w, http.StatusNotFound, "not found", "")
+	case len(parts) == 2 && parts[0] == "workspaces" && r.Method == http.MethodPatch:
+		ws, ok := f.workspaces[parts[1]]
+		if !ok {
+			writeErrors(w, http.StatusNotFound, "not found", "")
+			return
+		}
+		ws.Attributes = merge(ws.Attributes, in.Attributes)
+		writeData(w, http.StatusOK, ws)
+	case len(parts) == 4 && parts[0] == "workspaces" && parts[2] == "actions" && parts[3] == "safe-delete":
+		ws, ok := f.workspaces[parts[1]]
+		if !ok {
+			writeErrors(w, http.StatusNotFound, "not found", "")
+			return
+		}
+		a := workspaceAttributes{}
+		_ = json.Unmarshal(ws.Attributes, &a)
+		if a.ResourceCount > 0 {
+			writeErrors(w, http.StatusConflict, "conflict", "Workspace is currently managing resources")
+			return
+		}
+		delete(f.workspaces, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[0] == "workspaces" && parts[2] == "vars" && r.Method == http.MethodGet:
+		list := make([]*resource, 0, len(f.vars[parts[1]]))
+		for _, v := range f.vars[parts[1]] {
+			list = append(list, v)
+		}
+		writeData(w, http.StatusOK, list)
+	case len(parts) == 3 && parts[0] == "workspaces" && parts[2] == "vars" && r.Method == http.MethodPost:
+		if _, ok := f.workspaces[parts[1]]; !ok {
+			writeErrors(w, http.StatusNotFound, "not found", "")
+			return
+		}
+		if f.vars[parts[1]] == nil {
+			f.vars[parts[1]] = map[string]*resource{}
+		}
+		v := &resource{ID: f.id("var"), Type: "vars", Attributes: in.Attributes}
+		f.vars[parts[1]][v.ID] = v
+		writeData(w, http.StatusCreated, v)
+	case len(parts) == 4 && parts[0] == "workspaces" && parts[2] == "vars":
+		v, ok := f.vars[parts[1]][parts[3]]
+		if !ok {
+			writeErrors(w, http.StatusNotFound, "not found", "")
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			v.Attributes = merge(v.Attributes, in.Attributes)
+			writeData(w, http.StatusOK, v)
+		case http.MethodDelete:
+			delete(f.vars[parts[1]], parts[3])
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case len(parts) == 1 && parts[0] == "runs" && r.Method == http.MethodPost:
+		ws := in.Relationships["workspace"].Data
+		if ws == nil || f.workspaces[ws.ID] == nil {
+			writeErrors(w, http.StatusNotFound, "not found", "")
+			return
+		}
+		a := runAttributes{}
+		_ = json.Unmarshal(in.Attributes, &a)
+		a.Status = "pending"
+		run := &resource{ID: f.id("run"), Type: "runs", Attributes: mustJSON(a)}
+		f.runs[run.ID] = run
+		f.workspaces[ws.ID].Relationships = map[string]relationship{"current-run": {Data: &resourceID{ID: run.ID, Type: "runs"}}}
+		writeData(w, http.StatusCreated, run)
+	case len(parts) == 2 && parts[0] == "runs" && r.Method == http.MethodGet:
+		run, ok := f.runs[parts[1]]
+		if !ok {
+			writeErrors(w, http.StatusNotFound, "not found", "")
+			return
+		}
+		writeData(w, http.StatusOK, run)
+	default:
+		writeErrors(w, http.StatusNotFound, "not found", "")
+	}
+}
+
+func workspaceName(ws *resource) string {
+	a := workspaceAttributes{}
+	_ = json.Unmarshal(ws.Attributes, &a)
+	return a.Name
+}
+
+// merge returns the supplied attributes, updated by those of a PATCH.
+func merge(attrs, patch json.RawMessage) json.RawMessage {
+	m := map[string]any{}
+	_ = json.Unmarshal(attrs, &m)
+	_ = json.Unmarshal(patch, &m)
+	return mustJSON(m)
+}
+
+func mustJSON(v any) json.RawMessage {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+func writeData(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func writeErrors(w http.ResponseWriter, status int, title, detail string) {
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"errors": []map[string]string{{"title": title, "detail": detail}}})
+}
+
+func TestNewClient(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		address string
+		want    string
+		wantErr bool
+	}{
+		"Default": {
+			reason: "Terraform Cloud should be used if no address is supplied.",
+			want:   DefaultAddress,
+		},
+		"TrailingSlash": {
+			reason:  "A trailing slash should be trimmed from the address.",
+			address: "https://tfe.example.com/",
+			want:    "https://tfe.example.com",
+		},
+		"NoScheme": {
+			reason:  "An address without a scheme should be rejected.",
+			address: "tfe.example.com",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewClient(tc.address, testToken)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nNewClient(...): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, c.address); diff != "" {
+				t.Errorf("\n%s\nNewClient(...): -want address, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newFakeAPI(t)
+
+	created, err := c.CreateWorkspace(ctx, testOrg, WorkspaceOptions{Name: "network", TerraformVersion: "1.9.0", WorkingDirectory: "network"})
+	if err != nil {
+		t.Fatalf("CreateWorkspace(...): %v", err)
+	}
+	if created.ID == "" {
+		t.Errorf("CreateWorkspace(...): want ID, got none")
+	}
+	if _, err := c.CreateWorkspace(ctx, testOrg, WorkspaceOptions{Name: "network"}); err == nil || !strings.Contains(err.Error(), "Name has already been taken") {
+		t.Errorf("CreateWorkspace(...): want error describing the API's errors, got %v", err)
+	}
+
+	updated, err := c.UpdateWorkspace(ctx, created.ID, WorkspaceOptions{AutoApply: true, WorkingDirectory: "vpc"})
+	if err != nil {
+		t.Fatalf("UpdateWorkspace(...): %v", err)
+	}
+	want := &Workspace{ID: created.ID, Name: "network", AutoApply: true, TerraformVersion: "1.9.0", WorkingDirectory: "vpc"}
+	if diff := cmp.Diff(want, updated); diff != "" {
+		t.Errorf("UpdateWorkspace(...): -want, +got:\n%s", diff)
+	}
+
+	got, err := c.ReadWorkspace(ctx, testOrg, "network")
+	if err != nil {
+		t.Fatalf("ReadWorkspace(...): %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadWorkspace(...): -want, +got:\n%s", diff)
+	}
+
+	if err := c.SafeDeleteWorkspace(ctx, created.ID); err != nil {
+		t.Fatalf("SafeDeleteWorkspace(...): %v", err)
+	}
+	if _, err := c.ReadWorkspace(ctx, testOrg, "network"); !IsNotFound(err) {
+		t.Errorf("ReadWorkspace(...): want not found error, got %v", err)
+	}
+	if err := c.SafeDeleteWorkspace(ctx, created.ID); !IsNotFound(err) {
+		t.Errorf("SafeDeleteWorkspace(...): want not found error, got %v", err)
+	}
+}
+
+func TestSafeDeleteWorkspaceWithResources(t *testing.T) {
+	ctx := context.Background()
+	c, f := newFakeAPI(t)
+
+	ws, err := c.CreateWorkspace(ctx, testOrg, WorkspaceOptions{Name: "network"})
+	if err != nil {
+		t.Fatalf("CreateWorkspace(...): %v", err)
+	}
+	f.workspaces[ws.ID].Attributes = merge(f.workspaces[ws.ID].Attributes, mustJSON(map[string]int{"resource-count": 3}))
+
+	err = c.SafeDeleteWorkspace(ctx, ws.ID)
+	if err == nil || IsNotFound(err) || !strings.Contains(err.Error(), "409") {
+		t.Errorf("SafeDeleteWorkspace(...): want conflict error, got %v", err)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newFakeAPI(t)
+
+	ws, err := c.CreateWorkspace(ctx, testOrg, WorkspaceOptions{Name: "network"})
+	if err != nil {
+		t.Fatalf("CreateWorkspace(...): %v", err)
+	}
+	region := Variable{Key: "region", Value: "us-east-1", Category: CategoryTerraform, Description: "Managed by Crossplane"}
+	token := Variable{Key: "TF_LOG", Value: "DEBUG", Category: CategoryEnv}
+	for _, v := range []Variable{region, token} {
+		if err := c.CreateVariable(ctx, ws.ID, v); err != nil {
+			t.Fatalf("CreateVariable(...): %v", err)
+		}
+	}
+
+	list := func() []Variable {
+		t.Helper()
+		vars, err := c.ListVariables(ctx, ws.ID)
+		if err != nil {
+			t.Fatalf("ListVariables(...): %v", err)
+		}
+		return vars
+	}
+	byKey := cmpopts.SortSlices(func(a, b Variable) bool { return a.Key < b.Key })
+	ignoreID := cmpopts.IgnoreFields(Variable{}, "ID")
+
+	got := list()
+	if diff := cmp.Diff([]Variable{region, token}, got, byKey, ignoreID); diff != "" {
+		t.Errorf("ListVariables(...): -want, +got:\n%s", diff)
+	}
+	for _, v := range got {
+		if v.ID == "" {
+			t.Errorf("ListVariables(...): want ID of variable %s, got none", v.Key)
+		}
+		if v.Key == region.Key {
+			region.ID = v.ID
+		}
+		if v.Key == token.Key {
+			token.ID = v.ID
+		}
+	}
+
+	region.Value = "eu-west-1"
+	if err := c.UpdateVariable(ctx, ws.ID, region); err != nil {
+		t.Fatalf("UpdateVariable(...): %v", err)
+	}
+	if err := c.DeleteVariable(ctx, ws.ID, token.ID); err != nil {
+		t.Fatalf("DeleteVariable(...): %v", err)
+	}
+	if diff := cmp.Diff([]Variable{region}, list()); diff != "" {
+		t.Errorf("ListVariables(...): -want, +got:\n%s", diff)
+	}
+	if err := c.DeleteVariable(ctx, ws.ID, token.ID); !IsNotFound(err) {
+		t.Errorf("DeleteVariable(...): want not found error, got %v", err)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newFakeAPI(t)
+
+	ws, err := c.CreateWorkspace(ctx, testOrg, WorkspaceOptions{Name: "network"})
+	if err != nil {
+		t.Fatalf("CreateWorkspace(...): %v", err)
+	}
+	autoApply := true
+	run, err := c.CreateRun(ctx, ws.ID, RunOptions{Message: "destroy", IsDestroy: true, AutoApply: &autoApply})
+	if err != nil {
+		t.Fatalf("CreateRun(...): %v", err)
+	}
+	want := &Run{ID: run.ID, Status: "pending", Message: "destroy", IsDestroy: true}
+	if diff := cmp.Diff(want, run); diff != "" {
+		t.Errorf("CreateRun(...): -want, +got:\n%s", diff)
+	}
+
+	got, err := c.ReadRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("ReadRun(...): %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadRun(...): -want, +got:\n%s", diff)
+	}
+
+	current, err := c.ReadWorkspace(ctx, testOrg, "network")
+	if err != nil {
+		t.Fatalf("ReadWorkspace(...): %v", err)
+	}
+	if diff := cmp.Diff(run.ID, current.CurrentRunID); diff != "" {
+		t.Errorf("ReadWorkspace(...): -want current run, +got:\n%s", diff)
+	}
+
+	if _, err := c.CreateRun(ctx, "ws-missing", RunOptions{}); !IsNotFound(err) {
+		t.Errorf("CreateRun(...): want not found error, got %v", err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	c, _ := newFakeAPI(t)
+	c.token = "wrong"
+
+	_, err := c.ReadWorkspace(context.Background(), testOrg, "network")
+	if err == nil || IsNotFound(err) || !strings.Contains(err.Error(), "401") {
+		t.Errorf("ReadWorkspace(...): want unauthorized error, got %v", err)
+	}
+}
+
+func TestIsFinal(t *testing.T) {
+	cases := map[string]bool{
+		"pending":              false,
+		"planning":             false,
+		"applying":             false,
+		"applied":              true,
+		"planned_and_finished": true,
+		"errored":              true,
+		"discarded":            true,
+	}
+	for status, want := range cases {
+		if got := IsFinal(status); got != want {
+			t.Errorf("IsFinal(%q): want %t, got %t", status, want, got)
+		}
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		body   string
+		want   string
+	}{
+		"Errors": {
+			reason: "Each error should be described by its title and detail.",
+			body:   `{"errors":[{"title":"invalid attribute","detail":"Name has already been taken"},{"title":"conflict"}]}`,
+			want:   "invalid attribute: Name has already been taken; conflict",
+		},
+		"NotErrorDocument": {
+			reason: "A response that isn't an error document should be returned as is.",
+			body:   " Bad Gateway\n",
+			want:   "Bad Gateway",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, apiErrors([]byte(tc.body))); diff != "" {
+				t.Errorf("\n%s\napiErrors(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package validation
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+// backendRequiredKeys maps each backend Terraform supports to the
+// configuration keys it cannot be initialised without. Keys a backend can
+// also read from the environment, like the s3 backend's region, are not
+// required.
+var backendRequiredKeys = map[string][]string{
+	"local":      nil,
+	"remote":     {"organization"},
+	"azurerm":    {"storage_account_name", "container_name", "key"},
+	"consul":     {"path"},
+	"cos":        {"bucket"},
+	"gcs":        {"bucket"},
+	"http":       {"address"},
+	"kubernetes": {"secret_suffix"},
+	"oss":        {"bucket"},
+	"pg":         nil,
+	"s3":         {"bucket", "key"},
+}
+
+// Backend validates the supplied backend configuration.
+func Backend(b *v1alpha1.BackendConfig, path *field.Path) field.ErrorList {
+	if b == nil {
+		return nil
+	}
+
+	required, ok := backendRequiredKeys[b.Type]
+	if !ok {
+		return field.ErrorList{field.NotSupported(path.Child("type"), b.Type, backendTypes())}
+	}
+
+	var errs field.ErrorList
+	for _, k := range required {
+		if b.Configuration[k] == "" {
+			errs = append(errs, field.Required(path.Child("configuration").Key(k), "required by the "+b.Type+" backend"))
+		}
+	}
+	for k := range b.Configuration {
+		if !hclsyntax.ValidIdentifier(k) {
+			errs = append(errs, field.Invalid(path.Child("configuration").Key(k), k, errNotIdentifier))
+		}
+	}
+	return errs
+}
+
+func backendTypes() []string {
+	types := make([]string, 0, len(backendRequiredKeys))
+	for t := range backendRequiredKeys {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,230 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errNotPositive     = "must not be negative"
+	errNoCredentials   = "credentials source %s has no credentials to set it to"
+	errNoAdapt         = "credentials source %s has no credentials to adapt"
+	errProfileNotAWS   = "only AWS credentials have profiles"
+	errIdentitySource  = "requires the InjectedIdentity credentials source"
+	errNotRoleARN      = "must be the ARN of an IAM role"
+	errNotWIProvider   = "must be of the form projects/<number>/locations/global/workloadIdentityPools/<pool>/providers/<provider>"
+	errIdentityOnly    = "only %s workload identities have one"
+	errMinExpiration   = "must be at least 600"
+	errVaultSource     = "requires the Vault credentials source"
+	errNotVaultAddress = "must be an http or https URL"
+	errEnvOnlyKV       = "only KV secrets are mapped to environment variables"
+)
+
+var workloadIdentityProvider = regexp.MustCompile(`^projects/[^/]+/locations/global/workloadIdentityPools/[^/]+/providers/[^/]+$`)
+
+// ProviderConfig returns the errors in the spec of the supplied
+// ProviderConfig. The ProviderConfig controller uses it too, so that invalid
+// ProviderConfigs are reported when webhooks are disabled.
+func ProviderConfig(pc *v1alpha1.ProviderConfig) field.ErrorList {
+	p := field.NewPath("spec")
+
+	errs := validateCredentials(pc.Spec.Credentials, p.Child("credentials"))
+	errs = append(errs, Backend(pc.Spec.Backend, p.Child("backend"))...)
+	errs = append(errs, Environment(pc.Spec.Environment, p.Child("environment"))...)
+	errs = append(errs, TerraformVersion(pc.Spec.TerraformVersion, p.Child("terraformVersion"))...)
+	if pc.Spec.Parallelism < 0 {
+		errs = append(errs, field.Invalid(p.Child("parallelism"), pc.Spec.Parallelism, errNotPositive))
+	}
+	return errs
+}
+
+// validateCredentials checks that the selector the supplied credentials'
+// source reads from is set.
+func validateCredentials(c v1alpha1.ProviderCredentials, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch c.Source { //nolint:exhaustive // Other sources need no selector.
+	case xpv1.CredentialsSourceSecret:
+		p := path.Child("secretRef")
+		if c.SecretRef == nil {
+			return field.ErrorList{field.Required(p, "required when source is Secret")}
+		}
+		if c.SecretRef.Name == "" {
+			errs = append(errs, field.Required(p.Child("name"), ""))
+		}
+		if c.SecretRef.Namespace == "" {
+			errs = append(errs, field.Required(p.Child("namespace"), ""))
+		}
+		if c.SecretRef.Key == "" {
+			errs = append(errs, field.Required(p.Child("key"), ""))
+		}
+	case xpv1.CredentialsSourceEnvironment:
+		p := path.Child("env")
+		if c.Env == nil {
+			return field.ErrorList{field.Required(p, "required when source is Environment")}
+		}
+		if !EnvVarName.MatchString(c.Env.Name) {
+			errs = append(errs, field.Invalid(p.Child("name"), c.Env.Name, errNotEnvVar))
+		}
+	case xpv1.CredentialsSourceFilesystem:
+		p := path.Child("fs")
+		if c.Fs == nil {
+			return field.ErrorList{field.Required(p, "required when source is Filesystem")}
+		}
+		if c.Fs.Path == "" {
+			errs = append(errs, field.Required(p.Child("path"), ""))
+		}
+	case v1alpha1.CredentialsSourceVault:
+		if c.Vault == nil {
+			return field.ErrorList{field.Required(path.Child("vault"), "required when source is Vault")}
+		}
+	}
+
+	errs = append(errs, validateCredentialsVariable(c, c.EnvironmentVariable, path.Child("environmentVariable"))...)
+	errs = append(errs, validateCredentialsVariable(c, c.FileEnvironmentVariable, path.Child("fileEnvironmentVariable"))...)
+	if a := c.Adapter; a != nil {
+		p := path.Child("adapter")
+		if !hasCredentials(c.Source) {
+			errs = append(errs, field.Forbidden(p, fmt.Sprintf(errNoAdapt, c.Source)))
+		}
+		if a.Profile != "" && a.Type != v1alpha1.CredentialsAdapterAWS {
+			errs = append(errs, field.Forbidden(p.Child("profile"), errProfileNotAWS))
+		}
+	}
+	if w := c.WorkloadIdentity; w != nil {
+		p := path.Child("workloadIdentity")
+		if c.Source != xpv1.CredentialsSourceInjectedIdentity {
+			errs = append(errs, field.Forbidden(p, errIdentitySource))
+		}
+		errs = append(errs, validateWorkloadIdentity(w, p)...)
+	}
+	if v := c.Vault; v != nil {
+		p := path.Child("vault")
+		if c.Source != v1alpha1.CredentialsSourceVault {
+			errs = append(errs, field.Forbidden(p, errVaultSource))
+		}
+		errs = append(errs, validateVault(v, p)...)
+	}
+	return errs
+}
+
+// hasCredentials returns true if the supplied source reads credentials that
+// Terraform can be given as they are. Vault sets variables of its own.
+func hasCredentials(s xpv1.CredentialsSource) bool {
+	return s != xpv1.CredentialsSourceNone && s != xpv1.CredentialsSourceInjectedIdentity && s != v1alpha1.CredentialsSourceVault
+}
+
+// validateVault checks that the supplied Vault credentials can be read.
+func validateVault(v *v1alpha1.VaultCredentials, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if u, err := url.Parse(v.Address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, field.Invalid(path.Child("address"), v.Address, errNotVaultAddress))
+	}
+	if ref := v.CABundleSecretRef; ref != nil {
+		p := path.Child("caBundleSecretRef")
+		if ref.Name == "" {
+			errs = append(errs, field.Required(p.Child("name"), ""))
+		}
+		if ref.Namespace == "" {
+			errs = append(errs, field.Required(p.Child("namespace"), ""))
+		}
+		if ref.Key == "" {
+			errs = append(errs, field.Required(p.Child("key"), ""))
+		}
+	}
+	if v.Auth.Role == "" {
+		errs = append(errs, field.Required(path.Child("auth", "role"), ""))
+	}
+	errs = append(errs, validateServiceAccountRef(v.Auth.ServiceAccountRef, path.Child("auth", "serviceAccountRef"))...)
+
+	if len(v.Secrets) == 0 {
+		errs = append(errs, field.Required(path.Child("secrets"), ""))
+	}
+	for i, s := range v.Secrets {
+		p := path.Child("secrets").Index(i)
+		if s.Path == "" {
+			errs = append(errs, field.Required(p.Child("path"), ""))
+		}
+		if len(s.Env) > 0 && s.Engine != v1alpha1.VaultSecretKV {
+			errs = append(errs, field.Forbidden(p.Child("env"), errEnvOnlyKV))
+		}
+		for k, name := range s.Env {
+			if !EnvVarName.MatchString(name) {
+				errs = append(errs, field.Invalid(p.Child("env").Key(k), name, errNotEnvVar))
+			}
+		}
+	}
+	return errs
+}
+
+// validateServiceAccountRef checks that the supplied reference, if any,
+// names a service account.
+func validateServiceAccountRef(ref *v1alpha1.ServiceAccountReference, path *field.Path) field.ErrorList {
+	if ref == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if ref.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), ""))
+	}
+	if ref.Namespace == "" {
+		errs = append(errs, field.Required(path.Child("namespace"), ""))
+	}
+	return errs
+}
+
+// validateWorkloadIdentity checks that the supplied workload identity has
+// what its type needs to exchange a token, and nothing else.
+func validateWorkloadIdentity(w *v1alpha1.WorkloadIdentity, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch w.Type {
+	case v1alpha1.WorkloadIdentityAWS:
+		if w.Role == "" {
+			errs = append(errs, field.Required(path.Child("role"), "required for AWS"))
+		} else if !strings.HasPrefix(w.Role, "arn:") {
+			errs = append(errs, field.Invalid(path.Child("role"), w.Role, errNotRoleARN))
+		}
+	case v1alpha1.WorkloadIdentityGCP:
+		if !workloadIdentityProvider.MatchString(w.WorkloadIdentityProvider) {
+			errs = append(errs, field.Invalid(path.Child("workloadIdentityProvider"), w.WorkloadIdentityProvider, errNotWIProvider))
+		}
+	case v1alpha1.WorkloadIdentityAzure:
+		if w.Role == "" {
+			errs = append(errs, field.Required(path.Child("role"), "required for Azure"))
+		}
+		if w.TenantID == "" {
+			errs = append(errs, field.Required(path.Child("tenantID"), "required for Azure"))
+		}
+	}
+	if w.WorkloadIdentityProvider != "" && w.Type != v1alpha1.WorkloadIdentityGCP {
+		errs = append(errs, field.Forbidden(path.Child("workloadIdentityProvider"), fmt.Sprintf(errIdentityOnly, v1alpha1.WorkloadIdentityGCP)))
+	}
+	if w.TenantID != "" && w.Type != v1alpha1.WorkloadIdentityAzure {
+		errs = append(errs, field.Forbidden(path.Child("tenantID"), fmt.Sprintf(errIdentityOnly, v1alpha1.WorkloadIdentityAzure)))
+	}
+	errs = append(errs, validateServiceAccountRef(w.ServiceAccountRef, path.Child("serviceAccountRef"))...)
+	if e := w.ExpirationSeconds; e != nil && *e < 600 {
+		errs = append(errs, field.Invalid(path.Child("expirationSeconds"), *e, errMinExpiration))
+	}
+	return errs
+}
+
+// validateCredentialsVariable checks that the supplied environment variable,
+// if any, can be set from the supplied credentials.
+func validateCredentialsVariable(c v1alpha1.ProviderCredentials, v string, path *field.Path) field.ErrorList {
+	switch {
+	case v == "":
+		return nil
+	case !hasCredentials(c.Source):
+		return field.ErrorList{field.Forbidden(path, fmt.Sprintf(errNoCredentials, c.Source))}
+	case !EnvVarName.MatchString(v):
+		return field.ErrorList{field.Invalid(path, v, errNotEnvVar)}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errRemoteNoBackend = "must be set, as the referenced Workspace %s is a remote workspace, which has no backend to inherit"
+	errInvalidAddress  = "must be a resource address, e.g. aws_s3_bucket.example"
+	errNoSharedBackend = "references a Workspace without a backend; set one on the Workspace, so that it and the resources inheriting from it share their state"
+)
+
+// WorkspaceRef returns the errors in the state location the supplied
+// Terraform resource inherits from the supplied Workspace, which it
+// references. A Workspace only protects the resources in its state, so one
+// that isn't remote must have a backend its resources share. Those of a
+// remote Workspace need a backend of their own, or their state would be kept
+// in a local backend that nothing else reads.
+func WorkspaceRef(cr *v1alpha1.Terraform, ws *v1alpha1.Workspace) field.ErrorList {
+	if ws == nil {
+		return nil
+	}
+	p := field.NewPath("spec", "forProvider")
+	fp := ws.Spec.ForProvider
+	switch {
+	case fp.Remote != nil && cr.Spec.ForProvider.Backend == nil:
+		return field.ErrorList{field.Required(p.Child("backend"), fmt.Sprintf(errRemoteNoBackend, ws.GetName()))}
+	case fp.Remote == nil && fp.Backend == nil:
+		return field.ErrorList{field.Invalid(p.Child("workspaceRef", "name"), ws.GetName(), errNoSharedBackend)}
+	}
+	return nil
+}
+
+// Imports validates the supplied imports. Their addresses are written to the
+// configuration as is, so must be nothing but a resource address.
+func Imports(imports []v1alpha1.TerraformImport, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, imp := range imports {
+		p := path.Index(i).Child("address")
+		if _, diags := hclsyntax.ParseTraversalAbs([]byte(imp.Address), "", hcl.InitialPos); diags.HasErrors() {
+			errs = append(errs, field.Invalid(p, imp.Address, errInvalidAddress))
+			continue
+		}
+		if seen[imp.Address] {
+			errs = append(errs, field.Duplicate(p, imp.Address))
+		}
+		seen[imp.Address] = true
+	}
+	return errs
+}
@@ -0,0 +1,41 @@
+// Package validation validates the specs of the provider's resources. Both
+// the admission webhooks and the controllers use it, so that invalid
+// resources are reported even when the webhooks are disabled.
+package validation
+
+import (
+	"regexp"
+
+	"github.com/hashicorp/go-version"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	errNotEnvVar     = "must be a valid environment variable name"
+	errNotIdentifier = "must be a valid Terraform identifier"
+)
+
+// EnvVarName matches valid environment variable names.
+var EnvVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Environment validates the names of the supplied environment variables.
+func Environment(env map[string]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for k := range env {
+		if !EnvVarName.MatchString(k) {
+			errs = append(errs, field.Invalid(path.Key(k), k, errNotEnvVar))
+		}
+	}
+	return errs
+}
+
+// TerraformVersion validates the supplied Terraform version, if any.
+func TerraformVersion(v string, path *field.Path) field.ErrorList {
+	if v == "" {
+		return nil
+	}
+	if _, err := version.NewVersion(v); err != nil {
+		return field.ErrorList{field.Invalid(path, v, err.Error())}
+	}
+	return nil
+}
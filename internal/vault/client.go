@@ -0,0 +1,183 @@
+// Package vault is a client for the parts of the HashiCorp Vault HTTP API
+// used to read credentials: logging in with the Kubernetes auth method,
+// reading secrets and revoking their leases. It uses nothing but the HTTP
+// API, so works with any Vault compatible server.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultKubernetesMount is where the Kubernetes auth method is mounted
+	// by default.
+	DefaultKubernetesMount = "kubernetes"
+
+	apiPath = "/v1/"
+
+	errRequest  = "cannot %s %s"
+	errDecode   = "cannot decode response to %s %s"
+	errStatus   = "%s %s returned %d: %s"
+	errAddress  = "invalid Vault address %q"
+	errCABundle = "CA bundle contains no certificates"
+	errNoToken  = "login returned no token"
+)
+
+// A Client of the Vault API.
+type Client struct {
+	address   string
+	namespace string
+	token     string
+	http      *http.Client
+}
+
+// NewClient returns a client of the Vault server at the supplied address,
+// e.g. https://vault.example.com:8200, in the supplied Vault Enterprise
+// namespace, if any. The server's certificate is verified with the supplied
+// PEM encoded CA bundle, or the system's if it is empty.
+func NewClient(address, namespace string, caBundle []byte) (*Client, error) {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf(errAddress, address)
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // It always is.
+	if len(caBundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New(errCABundle)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &Client{
+		address:   strings.TrimSuffix(address, "/"),
+		namespace: namespace,
+		http:      &http.Client{Timeout: 30 * time.Second, Transport: t},
+	}, nil
+}
+
+// A Secret read from Vault.
+type Secret struct {
+	// LeaseID of the secret. Empty if it isn't leased, e.g. a KV value.
+	LeaseID string `json:"lease_id"`
+
+	// LeaseDuration is how many seconds the lease lasts.
+	LeaseDuration int64 `json:"lease_duration"`
+
+	// Renewable is true if the lease can be renewed.
+	Renewable bool `json:"renewable"`
+
+	// Data of the secret.
+	Data map[string]any `json:"data"`
+}
+
+// LoginKubernetes logs in with the Kubernetes auth method mounted at the
+// supplied path, as the supplied role, with the supplied service account
+// token. The client uses the Vault token it returns from then on.
+func (c *Client) LoginKubernetes(ctx context.Context, mount, role, jwt string) error {
+	if mount == "" {
+		mount = DefaultKubernetesMount
+	}
+	out := struct {
+		Auth *struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+	path := "auth/" + strings.Trim(mount, "/") + "/login"
+	if err := c.do(ctx, http.MethodPost, path, nil, map[string]string{"role": role, "jwt": jwt}, &out); err != nil {
+		return err
+	}
+	if out.Auth == nil || out.Auth.ClientToken == "" {
+		return errors.New(errNoToken)
+	}
+	c.token = out.Auth.ClientToken
+	return nil
+}
+
+// Read reads the secret at the supplied path, e.g. aws/creds/deploy. Secrets
+// engines that issue dynamic credentials take their parameters, e.g. ttl, as
+// query parameters.
+func (c *Client) Read(ctx context.Context, path string, params url.Values) (*Secret, error) {
+	s := &Secret{}
+	return s, c.do(ctx, http.MethodGet, strings.Trim(path, "/"), params, nil, s)
+}
+
+// Revoke revokes the identified lease.
+func (c *Client) Revoke(ctx context.Context, leaseID string) error {
+	return c.do(ctx, http.MethodPut, "sys/leases/revoke", nil, map[string]string{"lease_id": leaseID}, nil)
+}
+
+// RevokeSelf revokes the client's Vault token, and with it any leases it
+// was issued that weren't revoked.
+func (c *Client) RevokeSelf(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "auth/token/revoke-self", nil, nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return errors.Wrapf(err, errRequest, method, path)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	u := c.address + apiPath + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return errors.Wrapf(err, errRequest, method, path)
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, errRequest, method, path)
+	}
+	defer rsp.Body.Close() //nolint:errcheck // Nothing to do about it.
+
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return errors.Wrapf(err, errRequest, method, path)
+	}
+	if rsp.StatusCode >= 300 {
+		return errors.Errorf(errStatus, method, path, rsp.StatusCode, apiErrors(b))
+	}
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(b, out), errDecode, method, path)
+}
+
+// apiErrors returns the errors of the supplied Vault error response, or the
+// response itself if it isn't one.
+func apiErrors(b []byte) string {
+	d := struct {
+		Errors []string `json:"errors"`
+	}{}
+	if err := json.Unmarshal(b, &d); err != nil || len(d.Errors) == 0 {
+		return strings.TrimSpace(string(b))
+	}
+	return strings.Join(d.Errors, "; ")
+}
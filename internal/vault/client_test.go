@@ -0,0 +1,268 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	testJWT       = "service-account-token"
+	testToken     = "vault-token"
+	testNamespace = "team-a"
+)
+
+// A fakeVault is a stand-in for the parts of the Vault HTTP API the client
+// uses. It issues a leased AWS secret, and records what is revoked.
+type fakeVault struct {
+	mount       string
+	revoked     []string
+	selfRevoked bool
+}
+
+func newFakeVault(t *testing.T, mount string) (*Client, *fakeVault) {
+	t.Helper()
+	f := &fakeVault{mount: mount}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL+"/", testNamespace, nil)
+	if err != nil {
+		t.Fatalf("NewClient(...): %v", err)
+	}
+	return c, f
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Namespace") != testNamespace {
+		writeErrors(w, http.StatusBadRequest, "missing namespace")
+		return
+	}
+	body := map[string]string{}
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeErrors(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	if r.URL.Path == "/v1/auth/"+f.mount+"/login" && r.Method == http.MethodPost {
+		if body["role"] != "deploy" || body["jwt"] != testJWT {
+			writeErrors(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"auth": map[string]any{"client_token": testToken}})
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != testToken {
+		writeErrors(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v1/aws/creds/deploy" && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"lease_id":       "aws/creds/deploy/abc",
+			"lease_duration": 900,
+			"renewable":      true,
+			"data": map[string]any{
+				"access_key": "AKIA",
+				"secret_key": "secret",
+				"ttl":        r.URL.Query().Get("ttl"),
+			},
+		})
+	case r.URL.Path == "/v1/sys/leases/revoke" && r.Method == http.MethodPut:
+		f.revoked = append(f.revoked, body["lease_id"])
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/v1/auth/token/revoke-self" && r.Method == http.MethodPost:
+		f.selfRevoked = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	}
+}
+
+func writeErrors(w http.ResponseWriter, status int, errs ...string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"errors": errs})
+}
+
+func TestNewClient(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		address  string
+		caBundle []byte
+		wantErr  bool
+	}{
+		"HTTP": {
+			reason:  "Plain HTTP addresses, e.g. of a dev server, should be allowed.",
+			address: "http://127.0.0.1:8200",
+		},
+		"NoScheme": {
+			reason:  "Addresses without a scheme should be rejected.",
+			address: "vault.example.com:8200",
+			wantErr: true,
+		},
+		"OtherScheme": {
+			reason:  "Addresses of schemes other than HTTP should be rejected.",
+			address: "unix:///var/run/vault.sock",
+			wantErr: true,
+		},
+		"InvalidCABundle": {
+			reason:   "A CA bundle without certificates should be rejected.",
+			address:  "https://vault.example.com:8200",
+			caBundle: []byte("not a certificate"),
+			wantErr:  true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewClient(tc.address, "", tc.caBundle)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("\n%s\nNewClient(...): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoginKubernetes(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		mount   string
+		login   string
+		role    string
+		jwt     string
+		wantErr string
+	}{
+		"DefaultMount": {
+			reason: "The Kubernetes auth method should be mounted at its default path unless one is supplied.",
+			login:  DefaultKubernetesMount,
+			role:   "deploy",
+			jwt:    testJWT,
+		},
+		"Mount": {
+			reason: "The supplied mount path should be logged in at, without surrounding slashes.",
+			mount:  "/clusters/prod/",
+			login:  "clusters/prod",
+			role:   "deploy",
+			jwt:    testJWT,
+		},
+		"Denied": {
+			reason:  "An error response should be returned with the errors it describes.",
+			login:   DefaultKubernetesMount,
+			role:    "admin",
+			jwt:     testJWT,
+			wantErr: "POST auth/kubernetes/login returned 403: permission denied",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, _ := newFakeVault(t, tc.login)
+			err := c.LoginKubernetes(context.Background(), tc.mount, tc.role, tc.jwt)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(tc.wantErr, got); diff != "" {
+				t.Errorf("\n%s\nLoginKubernetes(...): -want error, +got:\n%s", tc.reason, diff)
+			}
+			if tc.wantErr == "" && c.token != testToken {
+				t.Errorf("\n%s\nLoginKubernetes(...): want client to use the token it was issued", tc.reason)
+			}
+		})
+	}
+}
+
+func TestLoginKubernetesNoToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"auth":null}`))
+	}))
+	defer srv.Close()
+	c, err := NewClient(srv.URL, "", nil)
+	if err != nil {
+		t.Fatalf("NewClient(...): %v", err)
+	}
+	if err := c.LoginKubernetes(context.Background(), "", "deploy", testJWT); err == nil || err.Error() != errNoToken {
+		t.Errorf("LoginKubernetes(...): want %q, got %v", errNoToken, err)
+	}
+}
+
+func TestReadAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	c, f := newFakeVault(t, DefaultKubernetesMount)
+
+	if _, err := c.Read(ctx, "aws/creds/deploy", nil); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Read(...): want permission denied before logging in, got %v", err)
+	}
+	if err := c.LoginKubernetes(ctx, "", "deploy", testJWT); err != nil {
+		t.Fatalf("LoginKubernetes(...): %v", err)
+	}
+
+	s, err := c.Read(ctx, "/aws/creds/deploy/", url.Values{"ttl": []string{"900s"}})
+	if err != nil {
+		t.Fatalf("Read(...): %v", err)
+	}
+	want := &Secret{
+		LeaseID:       "aws/creds/deploy/abc",
+		LeaseDuration: 900,
+		Renewable:     true,
+		Data:          map[string]any{"access_key": "AKIA", "secret_key": "secret", "ttl": "900s"},
+	}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("Read(...): -want, +got:\n%s", diff)
+	}
+
+	if _, err := c.Read(ctx, "aws/creds/missing", nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Read(...): want not found error, got %v", err)
+	}
+
+	if err := c.Revoke(ctx, s.LeaseID); err != nil {
+		t.Fatalf("Revoke(...): %v", err)
+	}
+	if diff := cmp.Diff([]string{s.LeaseID}, f.revoked); diff != "" {
+		t.Errorf("Revoke(...): -want revoked, +got:\n%s", diff)
+	}
+	if err := c.RevokeSelf(ctx); err != nil {
+		t.Fatalf("RevokeSelf(...): %v", err)
+	}
+	if !f.selfRevoked {
+		t.Errorf("RevokeSelf(...): want token revoked")
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		body   string
+		want   string
+	}{
+		"Errors": {
+			reason: "The errors of an error response should be joined.",
+			body:   `{"errors":["permission denied","invalid token"]}`,
+			want:   "permission denied; invalid token",
+		},
+		"NoErrors": {
+			reason: "A response without errors should be returned as is.",
+			body:   "{\"errors\":[]}\n",
+			want:   `{"errors":[]}`,
+		},
+		"NotJSON": {
+			reason: "A response that isn't JSON should be returned as is.",
+			body:   "upstream connect error\n",
+			want:   "upstream connect error",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, apiErrors([]byte(tc.body))); diff != "" {
+				t.Errorf("\n%s\napiErrors(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
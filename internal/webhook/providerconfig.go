@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/validation"
+)
+
+const errNotProviderConfig = "object is not a ProviderConfig"
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-terraform-crossplane-io-v1alpha1-providerconfig,mutating=false,failurePolicy=fail,groups=terraform.crossplane.io,resources=providerconfigs,versions=v1alpha1,name=providerconfigs.terraform.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+
+// A ProviderConfigValidator validates ProviderConfigs.
+type ProviderConfigValidator struct{}
+
+// ValidateCreate validates a ProviderConfig being created.
+func (v *ProviderConfigValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	pc, ok := obj.(*v1alpha1.ProviderConfig)
+	if !ok {
+		return nil, errors.New(errNotProviderConfig)
+	}
+	return nil, invalid(v1alpha1.ProviderConfigGroupKind, pc.GetName(), validation.ProviderConfig(pc))
+}
+
+// ValidateUpdate validates a ProviderConfig being updated.
+func (v *ProviderConfigValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return v.ValidateCreate(ctx, newObj)
+}
+
+// ValidateDelete does nothing; ProviderConfigs may always be deleted.
+func (v *ProviderConfigValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
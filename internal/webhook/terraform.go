@@ -0,0 +1,352 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfconfig"
+	"github.com/mgeorge67701/crossplane-terraform/internal/validation"
+)
+
+const (
+	errNotTerraform      = "object is not a Terraform resource"
+	errNotIdentifier     = "must be a valid Terraform identifier"
+	errNotWorkspaceName  = "must be a valid Terraform workspace name, which cannot contain characters that need escaping in a URL"
+	errReservedVariable  = "is reserved by Terraform and cannot be used as a variable name"
+	errAzureRegion       = "azurerm has no region; its resources set their own location"
+	errSourceVariants    = "exactly one of path, git or http must be set"
+	errVariableSources   = "must set exactly one of output with terraformRef or terraformSelector, fieldRef, or secretKeyRef"
+	errAPIVersion        = "must be an API version, e.g. v1 or s3.aws.upbound.io/v1beta1"
+	errRefOrSelector     = "either terraformRef or terraformSelector must be set"
+	errGetWorkspace      = "cannot get referenced Workspace"
+	errImmutableLocation = "cannot be changed once the state has been initialised unless the resource is annotated with " + v1alpha1.AnnotationKeyMigrateState + "=true"
+)
+
+// reservedVariableNames cannot be declared as variables by a Terraform
+// module.
+var reservedVariableNames = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"providers":  true,
+	"count":      true,
+	"for_each":   true,
+	"lifecycle":  true,
+	"depends_on": true,
+	"locals":     true,
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-terraform-crossplane-io-v1alpha1-terraform,mutating=false,failurePolicy=fail,groups=terraform.crossplane.io,resources=terraforms,versions=v1alpha1,name=terraforms.terraform.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+
+// A TerraformValidator validates Terraform resources.
+type TerraformValidator struct {
+	kube client.Reader
+}
+
+// ValidateCreate validates a Terraform resource being created.
+func (v *TerraformValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	cr, ok := obj.(*v1alpha1.Terraform)
+	if !ok {
+		return nil, errors.New(errNotTerraform)
+	}
+	ws, err := v.workspace(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+	return nil, invalid(v1alpha1.TerraformGroupKind, cr.GetName(), validateTerraform(cr, ws))
+}
+
+// ValidateUpdate validates a Terraform resource being updated. The backend
+// and workspace of a resource whose state has been initialised may only be
+// changed if its state is to be migrated. Those of a resource referencing a
+// Workspace may come from the Workspace, so are checked by the controller.
+func (v *TerraformValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCR, ok := oldObj.(*v1alpha1.Terraform)
+	if !ok {
+		return nil, errors.New(errNotTerraform)
+	}
+	cr, ok := newObj.(*v1alpha1.Terraform)
+	if !ok {
+		return nil, errors.New(errNotTerraform)
+	}
+	ws, err := v.workspace(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+	errs := validateTerraform(cr, ws)
+	inherits := oldCR.Spec.ForProvider.WorkspaceRef != nil || cr.Spec.ForProvider.WorkspaceRef != nil
+	if oldCR.Status.AtProvider.StateLocation != nil && !inherits && cr.GetAnnotations()[v1alpha1.AnnotationKeyMigrateState] != "true" {
+		errs = append(errs, validateStateLocationUnchanged(oldCR, cr)...)
+	}
+	return nil, invalid(v1alpha1.TerraformGroupKind, cr.GetName(), errs)
+}
+
+// ValidateDelete does nothing; Terraform resources may always be deleted.
+func (v *TerraformValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// workspace returns the Workspace the supplied resource references, or nil
+// if it references none or none that exists yet. A Workspace created later is
+// checked by the controller.
+func (v *TerraformValidator) workspace(ctx context.Context, cr *v1alpha1.Terraform) (*v1alpha1.Workspace, error) {
+	ref := cr.Spec.ForProvider.WorkspaceRef
+	if ref == nil {
+		return nil, nil
+	}
+	ws := &v1alpha1.Workspace{}
+	err := v.kube.Get(ctx, types.NamespacedName{Name: ref.Name}, ws)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return ws, errors.Wrap(err, errGetWorkspace)
+}
+
+// validateStateLocationUnchanged returns an error for each field locating
+// the state that differs between the supplied resources.
+func validateStateLocationUnchanged(oldCR, cr *v1alpha1.Terraform) field.ErrorList {
+	p := field.NewPath("spec", "forProvider")
+	prev := oldCR.Spec.ForProvider.StateLocation()
+	next := cr.Spec.ForProvider.StateLocation()
+
+	var errs field.ErrorList
+	if prev.Workspace != next.Workspace {
+		errs = append(errs, field.Forbidden(p.Child("workspace"), errImmutableLocation))
+	}
+	prev.Workspace = next.Workspace
+	if !prev.Equal(next) {
+		errs = append(errs, field.Forbidden(p.Child("backend"), errImmutableLocation))
+	}
+	return errs
+}
+
+func validateTerraform(cr *v1alpha1.Terraform, ws *v1alpha1.Workspace) field.ErrorList {
+	p := field.NewPath("spec", "forProvider")
+	fp := cr.Spec.ForProvider
+
+	errs := validateConfiguration(fp.Configuration.Raw, p.Child("configuration"))
+	errs = append(errs, validateFiles(fp.Files, p.Child("files"))...)
+	errs = append(errs, validation.Backend(fp.Backend, p.Child("backend"))...)
+	errs = append(errs, validation.WorkspaceRef(cr, ws)...)
+	errs = append(errs, validateSource(fp.Source, p.Child("source"))...)
+	errs = append(errs, validateVariables(fp.Variables, p.Child("variables"))...)
+	errs = append(errs, validateVariablesFrom(fp.VariablesFrom, fp.Variables, p.Child("variablesFrom"))...)
+	errs = append(errs, validation.Imports(fp.Imports, p.Child("imports"))...)
+	errs = append(errs, validateProviderAliases(fp.ProviderAliases, p.Child("providerAliases"))...)
+	if fp.Workspace != "" && !validWorkspaceName(fp.Workspace) {
+		errs = append(errs, field.Invalid(p.Child("workspace"), fp.Workspace, errNotWorkspaceName))
+	}
+	return errs
+}
+
+// validateConfiguration parses the supplied configuration, which is either a
+// string of HCL or an object in Terraform's JSON configuration syntax.
+func validateConfiguration(raw []byte, path *field.Path) field.ErrorList {
+	name, content, err := tfconfig.Main(raw)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, "", err.Error())}
+	}
+
+	_, diags := tfconfig.Parse(name, content)
+	errs := make(field.ErrorList, 0, len(diags.Errs()))
+	for _, err := range diags.Errs() {
+		errs = append(errs, field.Invalid(path, "", err.Error()))
+	}
+	return errs
+}
+
+// validateFiles checks that the path of each of the supplied configuration
+// files stays within the working directory, and parses any Terraform files.
+func validateFiles(files map[string]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for name, content := range files {
+		p := path.Key(name)
+		if err := tfconfig.ValidatePath(name); err != nil {
+			errs = append(errs, field.Invalid(p, name, err.Error()))
+			continue
+		}
+		if !tfconfig.IsConfigFile(name) {
+			continue
+		}
+		_, diags := tfconfig.Parse(name, []byte(content))
+		for _, err := range diags.Errs() {
+			errs = append(errs, field.Invalid(p, "", err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateSource(s *v1alpha1.TerraformSource, path *field.Path) field.ErrorList {
+	if s == nil {
+		return nil
+	}
+	n := 0
+	if s.Path != "" {
+		n++
+	}
+	if s.Git != nil {
+		n++
+	}
+	if s.HTTP != nil {
+		n++
+	}
+	if n != 1 {
+		return field.ErrorList{field.Invalid(path, fmt.Sprintf("%d variants", n), errSourceVariants)}
+	}
+	var errs field.ErrorList
+	if s.Git != nil {
+		errs = append(errs, validateURL(s.Git.URL, path.Child("git", "url"))...)
+	}
+	if s.HTTP != nil {
+		errs = append(errs, validateURL(s.HTTP.URL, path.Child("http", "url"))...)
+	}
+	return errs
+}
+
+func validateURL(u string, path *field.Path) field.ErrorList {
+	if u == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	if _, err := url.Parse(u); err != nil {
+		return field.ErrorList{field.Invalid(path, u, err.Error())}
+	}
+	return nil
+}
+
+func validateVariables(vars map[string]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for k := range vars {
+		switch {
+		case !validIdentifier(k):
+			errs = append(errs, field.Invalid(path.Key(k), k, errNotIdentifier))
+		case reservedVariableNames[k]:
+			errs = append(errs, field.Invalid(path.Key(k), k, errReservedVariable))
+		}
+	}
+	return errs
+}
+
+func validateProviderAliases(aliases []v1alpha1.ProviderAlias, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, a := range aliases {
+		p := path.Index(i)
+		key := a.Provider + "." + a.Alias
+		switch {
+		case !validIdentifier(a.Alias):
+			errs = append(errs, field.Invalid(p.Child("alias"), a.Alias, errNotIdentifier))
+		case seen[key]:
+			errs = append(errs, field.Duplicate(p.Child("alias"), key))
+		}
+		seen[key] = true
+		if a.Region != "" && a.Provider == "azurerm" {
+			errs = append(errs, field.Forbidden(p.Child("region"), errAzureRegion))
+		}
+		if a.ProviderConfigRef.Name == "" {
+			errs = append(errs, field.Required(p.Child("providerConfigRef", "name"), ""))
+		}
+	}
+	return errs
+}
+
+func validateVariablesFrom(from []v1alpha1.VariableFrom, vars map[string]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, v := range from {
+		p := path.Index(i)
+		switch {
+		case !validIdentifier(v.Name):
+			errs = append(errs, field.Invalid(p.Child("name"), v.Name, errNotIdentifier))
+		case reservedVariableNames[v.Name]:
+			errs = append(errs, field.Invalid(p.Child("name"), v.Name, errReservedVariable))
+		case seen[v.Name]:
+			errs = append(errs, field.Duplicate(p.Child("name"), v.Name))
+		default:
+			if _, ok := vars[v.Name]; ok {
+				errs = append(errs, field.Duplicate(p.Child("name"), v.Name))
+			}
+		}
+		seen[v.Name] = true
+		fromOutput := v.Output != "" || v.TerraformRef != nil || v.TerraformSelector != nil
+		switch {
+		case countTrue(fromOutput, v.FieldRef != nil, v.SecretKeyRef != nil) > 1:
+			errs = append(errs, field.Invalid(p, v.Name, errVariableSources))
+		case v.FieldRef != nil:
+			errs = append(errs, validateFieldRef(v.FieldRef, p.Child("fieldRef"))...)
+		case v.SecretKeyRef != nil:
+			errs = append(errs, validateSecretKeyRef(v.SecretKeyRef, p.Child("secretKeyRef"))...)
+		default:
+			if !validIdentifier(v.Output) {
+				errs = append(errs, field.Invalid(p.Child("output"), v.Output, errNotIdentifier))
+			}
+			if v.TerraformRef == nil && v.TerraformSelector == nil {
+				errs = append(errs, field.Required(p.Child("terraformRef"), errRefOrSelector))
+			}
+		}
+	}
+	return errs
+}
+
+func validateFieldRef(s *v1alpha1.ObjectFieldSelector, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if _, err := schema.ParseGroupVersion(s.APIVersion); err != nil || s.APIVersion == "" {
+		errs = append(errs, field.Invalid(path.Child("apiVersion"), s.APIVersion, errAPIVersion))
+	}
+	if s.Kind == "" {
+		errs = append(errs, field.Required(path.Child("kind"), ""))
+	}
+	if s.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), ""))
+	}
+	if s.FieldPath == "" {
+		errs = append(errs, field.Required(path.Child("fieldPath"), ""))
+	}
+	return errs
+}
+
+func validateSecretKeyRef(s *xpv1.SecretKeySelector, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if s.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), ""))
+	}
+	if s.Namespace == "" {
+		errs = append(errs, field.Required(path.Child("namespace"), ""))
+	}
+	if s.Key == "" {
+		errs = append(errs, field.Required(path.Child("key"), ""))
+	}
+	return errs
+}
+
+func countTrue(bs ...bool) int {
+	n := 0
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+	return n
+}
+
+// validIdentifier returns true if s is a valid Terraform identifier.
+func validIdentifier(s string) bool {
+	return hclsyntax.ValidIdentifier(s)
+}
+
+// validWorkspaceName returns true if s is a name Terraform accepts for a
+// workspace.
+func validWorkspaceName(s string) bool {
+	return s != "" && url.PathEscape(s) == s
+}
@@ -0,0 +1,44 @@
+// Package webhook validates Terraform provider resources at admission time,
+// so that malformed resources are rejected when they are applied rather than
+// failing on their next reconcile.
+package webhook
+
+import (
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+)
+
+const (
+	errSetupTerraform      = "cannot setup Terraform webhook"
+	errSetupWorkspace      = "cannot setup Workspace webhook"
+	errSetupProviderConfig = "cannot setup ProviderConfig webhook"
+)
+
+// Setup adds the validating webhooks of all resources to the supplied
+// manager's webhook server.
+func Setup(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).For(&v1alpha1.Terraform{}).WithValidator(&TerraformValidator{kube: mgr.GetClient()}).Complete(); err != nil {
+		return errors.Wrap(err, errSetupTerraform)
+	}
+	if err := ctrl.NewWebhookManagedBy(mgr).For(&v1alpha1.Workspace{}).WithValidator(&WorkspaceValidator{}).Complete(); err != nil {
+		return errors.Wrap(err, errSetupWorkspace)
+	}
+	if err := ctrl.NewWebhookManagedBy(mgr).For(&v1alpha1.ProviderConfig{}).WithValidator(&ProviderConfigValidator{}).Complete(); err != nil {
+		return errors.Wrap(err, errSetupProviderConfig)
+	}
+	return nil
+}
+
+// invalid returns an Invalid error for the named resource if there are any
+// supplied errors.
+func invalid(gk schema.GroupKind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return kerrors.NewInvalid(gk, name, errs)
+}
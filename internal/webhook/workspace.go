@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
+	"github.com/mgeorge67701/crossplane-terraform/internal/tfconfig"
+	"github.com/mgeorge67701/crossplane-terraform/internal/validation"
+)
+
+const (
+	errNotWorkspace  = "object is not a Workspace"
+	errImmutableName = "cannot be changed; create a new Workspace instead"
+	errRemoteBackend = "a remote workspace has no backend"
+	errAddress       = "must be an absolute URL, e.g. https://app.terraform.io"
+)
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-terraform-crossplane-io-v1alpha1-workspace,mutating=false,failurePolicy=fail,groups=terraform.crossplane.io,resources=workspaces,versions=v1alpha1,name=workspaces.terraform.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+
+// A WorkspaceValidator validates Workspace resources.
+type WorkspaceValidator struct{}
+
+// ValidateCreate validates a Workspace being created.
+func (v *WorkspaceValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ws, ok := obj.(*v1alpha1.Workspace)
+	if !ok {
+		return nil, errors.New(errNotWorkspace)
+	}
+	return nil, invalid(v1alpha1.WorkspaceGroupKind, ws.GetName(), validateWorkspace(ws))
+}
+
+// ValidateUpdate validates a Workspace being updated. A Workspace can't be
+// renamed, which would create a new workspace and orphan the old one.
+func (v *WorkspaceValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*v1alpha1.Workspace)
+	if !ok {
+		return nil, errors.New(errNotWorkspace)
+	}
+	ws, ok := newObj.(*v1alpha1.Workspace)
+	if !ok {
+		return nil, errors.New(errNotWorkspace)
+	}
+	errs := validateWorkspace(ws)
+	if old.Spec.ForProvider.Name != ws.Spec.ForProvider.Name {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "forProvider", "name"), errImmutableName))
+	}
+	return nil, invalid(v1alpha1.WorkspaceGroupKind, ws.GetName(), errs)
+}
+
+// ValidateDelete does nothing; Workspaces may always be deleted.
+func (v *WorkspaceValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateWorkspace(ws *v1alpha1.Workspace) field.ErrorList {
+	p := field.NewPath("spec", "forProvider")
+	fp := ws.Spec.ForProvider
+
+	var errs field.ErrorList
+	if !validWorkspaceName(fp.Name) {
+		errs = append(errs, field.Invalid(p.Child("name"), fp.Name, errNotWorkspaceName))
+	}
+	errs = append(errs, validation.Backend(fp.Backend, p.Child("backend"))...)
+	if fp.Remote != nil {
+		if fp.Backend != nil {
+			errs = append(errs, field.Forbidden(p.Child("backend"), errRemoteBackend))
+		}
+		errs = append(errs, validateRemote(fp.Remote, p.Child("remote"))...)
+	}
+	errs = append(errs, validateVariables(fp.Variables, p.Child("variables"))...)
+	errs = append(errs, validation.Environment(fp.Environment, p.Child("environment"))...)
+	errs = append(errs, validation.TerraformVersion(fp.TerraformVersion, p.Child("terraformVersion"))...)
+	if fp.WorkingDirectory != "" {
+		if err := tfconfig.ValidatePath(fp.WorkingDirectory); err != nil {
+			errs = append(errs, field.Invalid(p.Child("workingDirectory"), fp.WorkingDirectory, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateRemote(r *v1alpha1.RemoteWorkspace, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if r.Address != "" {
+		if u, err := url.Parse(r.Address); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, field.Invalid(path.Child("address"), r.Address, errAddress))
+		}
+	}
+	if r.Organization == "" {
+		errs = append(errs, field.Required(path.Child("organization"), ""))
+	}
+	errs = append(errs, validateSecretKeyRef(&r.TokenSecretRef, path.Child("tokenSecretRef"))...)
+	return errs
+}
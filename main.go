@@ -3,19 +3,23 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/feature"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"gopkg.in/alecthomas/kingpin.v2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/mgeorge67701/crossplane-terraform/apis/terraform/v1alpha1"
 	terraformcontroller "github.com/mgeorge67701/crossplane-terraform/internal/controller"
+	terraformwebhook "github.com/mgeorge67701/crossplane-terraform/internal/webhook"
 )
 
 func main() {
@@ -29,6 +33,9 @@ func main() {
 		enableExternalSecretStores = app.Flag("enable-external-secret-stores", "Enable support for ExternalSecretStores.").Default("false").Bool()
 		enableManagementPolicies   = app.Flag("enable-management-policies", "Enable support for ManagementPolicies.").Default("true").Bool()
 		essTLSCertsPath            = app.Flag("ess-tls-cert-dir", "Path of ESS TLS certificates.").String()
+		enableWebhooks             = app.Flag("enable-webhooks", "Serve the validating admission webhooks.").Default("true").Bool()
+		variableSources            = app.Flag("variable-source", "A kind of Kubernetes object whose fields Terraform variables may be read from, as apiVersion/kind, e.g. v1/Service. May be repeated.").Strings()
+		certsDir                   = app.Flag("certs-dir", "The directory that contains the webhook server key and certificate.").Default("/tls/server").Envar("TLS_SERVER_CERTS_DIR").String()
 	)
 
 	kingpin.MustParse(app.Parse(os.Args[1:]))
@@ -67,6 +74,9 @@ func main() {
 		LeaderElectionID: "crossplane-terraform-provider-leader-election-helper",
 		Cache:            cache.Options{SyncPeriod: syncInterval},
 		Logger:           ctrl.Log.WithName("manager"),
+		WebhookServer: webhook.NewServer(webhook.Options{
+			CertDir: *certsDir,
+		}),
 	})
 	if err != nil {
 		log.Info("Cannot create manager", "error", err)
@@ -90,8 +100,18 @@ func main() {
 		}
 	}
 
+	var to terraformcontroller.TerraformOptions
+	for _, vs := range *variableSources {
+		i := strings.LastIndex(vs, "/")
+		if i <= 0 || i == len(vs)-1 {
+			log.Info("Invalid variable source, expected apiVersion/kind", "variable-source", vs)
+			os.Exit(1)
+		}
+		to.VariableSources = append(to.VariableSources, schema.FromAPIVersionAndKind(vs[:i], vs[i+1:]))
+	}
+
 	// Setup controllers
-	if err := terraformcontroller.SetupTerraform(mgr, o); err != nil {
+	if err := terraformcontroller.SetupTerraform(mgr, o, to); err != nil {
 		log.Info("Cannot setup Terraform controller", "error", err)
 		os.Exit(1)
 	}
@@ -106,6 +126,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *enableWebhooks {
+		if err := terraformwebhook.Setup(mgr); err != nil {
+			log.Info("Cannot setup webhooks", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	log.Info("Starting manager - Crossplane Terraform Provider")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		log.Info("Cannot start manager", "error", err)